@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/netip"
 	"os"
 	"time"
 
@@ -15,10 +16,28 @@ import (
 	infraConfig "github.com/SilentPlaces/rate_limiter/internal/infrastructure/config"
 	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/consul"
 	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/memory"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/metrics"
 	redis2 "github.com/SilentPlaces/rate_limiter/internal/infrastructure/redis"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/resilience"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/tracing"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/upstream"
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/check"
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/envoy"
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/grpcauth"
 	handler "github.com/SilentPlaces/rate_limiter/internal/interfaces/http"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
 	"github.com/hashicorp/consul/api"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+)
+
+const (
+	multiLimitScriptPath  = "scripts/lua/multi_limit.lua"
+	penaltyScriptPath     = "scripts/lua/penalty.lua"
+	tracerShutdownTimeout = 5 * time.Second
+	upstreamPruneInterval = 30 * time.Second
 )
 
 // Container holds all initialized application services and their dependencies.
@@ -30,9 +49,22 @@ type Container struct {
 	ConfigService      *service.ConfigService
 	RateLimiterService *service.LimiterService
 	HTTPHandler        http.Handler
+	AdminHandler       http.Handler
+	GRPCServer         *grpc.Server
+	TrustedProxies     domainConfig.IPRanges
+	Tracer             *tracing.OtelTracer
 }
 
 func (c *Container) Close() {
+	if c.Tracer != nil {
+		c.Log.Info("Flushing traces...")
+		ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+		defer cancel()
+		if err := c.Tracer.Shutdown(ctx); err != nil {
+			c.Log.Error("failed to shut down tracer", ports.Field{Key: "err", Val: err.Error()})
+		}
+	}
+
 	if c.RedisClient != nil {
 		c.Log.Info("Closing Redis connection...")
 		if err := c.RedisClient.Close(); err != nil {
@@ -43,34 +75,48 @@ func (c *Container) Close() {
 
 // New builds dependencies using already created logger and loaded config.
 func New(ctx context.Context, log ports.Logger, cfg *config.Config) (*Container, error) {
-	// Initialize Redis
-	rc, err := newRedisClient(cfg.Redis)
+	// Initialize Redis, unless limiter state is kept in memory
+	var rc *redis.Client
+	if cfg.Store.Type != config.StoreMemory {
+		var err error
+		rc, err = newRedisClient(cfg.Redis)
+		if err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+	}
+
+	// Metrics
+	promMetrics := metrics.NewPrometheusMetrics()
+
+	// Tracing
+	tracer, err := tracing.NewOtelTracer(ctx, cfg.Tracing)
 	if err != nil {
-		return nil, fmt.Errorf("redis: %w", err)
+		closeRedis(rc)
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	log.Info("Tracer initialized", ports.Field{Key: "exporter", Val: cfg.Tracing.Exporter})
+
+	// release closes what was created so far when a later step fails
+	release := func() {
+		shutdownTracer(tracer)
+		closeRedis(rc)
 	}
 
 	// Initialize Consul
 	cc, err := newConsulClient(cfg.Consul)
 	if err != nil {
-		_ = rc.Close()
+		release()
 		return nil, fmt.Errorf("consul: %w", err)
 	}
 
 	// Adapters
 	configParser := infraConfig.NewParser()
 	consulAdapter := consul.NewConsulAdapter(cc, configParser, log)
-	baseRedisAdapter := redis2.NewRedisAdapter(rc, log, cfg.Redis.OperationTimeoutSeconds)
-	redisAdapter := redis2.NewResilientRedisAdapter(
-		baseRedisAdapter,
-		log,
-		cfg.Redis.CircuitBreakerMaxFailures,
-		time.Duration(cfg.Redis.CircuitBreakerTimeoutSeconds)*time.Second,
-	)
 
 	// Create Config Service
-	cfgSvc := service.NewConfigService(consulAdapter, log, cfg.App)
+	cfgSvc := service.NewConfigService(consulAdapter, log, cfg.App, promMetrics)
 	if err := cfgSvc.LoadOnce(ctx, cfg.App.ConfigKey); err != nil {
-		_ = rc.Close()
+		release()
 		return nil, fmt.Errorf("config load: %w", err)
 	}
 	cfgSvc.WatchConfig(ctx, cfg.App.ConfigKey)
@@ -81,17 +127,51 @@ func New(ctx context.Context, log ports.Logger, cfg *config.Config) (*Container,
 		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmFixedWindow),
 		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmTokenBucket),
 		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmSlidingWindow),
+		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmLeakyBucket),
+		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmGCRA),
+		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmSlidingWindowCounter),
+		fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmConcurrency),
+		multiLimitScriptPath,
+		penaltyScriptPath,
 	}, log)
 	if err != nil {
-		_ = rc.Close()
+		release()
 		return nil, fmt.Errorf("lua files: %w", err)
 	}
 	log.Info("Lua files loaded from disk", ports.Field{Key: "count", Val: len(luaFiles)})
 
-	// Load scripts into Redis and get SHA1 hashes
-	scriptSHA1s, err := loadScriptsIntoRedis(ctx, redisAdapter, luaFiles, log)
+	// Build the stacked limits script from every algorithm script
+	algorithmScriptPaths := map[string]string{
+		domainConfig.AlgorithmFixedWindow:          fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmFixedWindow),
+		domainConfig.AlgorithmTokenBucket:          fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmTokenBucket),
+		domainConfig.AlgorithmSlidingWindow:        fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmSlidingWindow),
+		domainConfig.AlgorithmLeakyBucket:          fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmLeakyBucket),
+		domainConfig.AlgorithmGCRA:                 fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmGCRA),
+		domainConfig.AlgorithmSlidingWindowCounter: fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmSlidingWindowCounter),
+		domainConfig.AlgorithmConcurrency:          fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmConcurrency),
+	}
+	if body, ok := luaFiles[multiLimitScriptPath]; ok {
+		algorithmScripts := make(map[string]string, len(algorithmScriptPaths))
+		for algo, scriptPath := range algorithmScriptPaths {
+			if script, ok := luaFiles[scriptPath]; ok {
+				algorithmScripts[algo] = script
+			}
+		}
+		luaFiles[multiLimitScriptPath] = limiter.BuildMultiLimitScript(body, algorithmScripts)
+	}
+
+	// Limiter store
+	store, circuitBreaker, err := newLimiterStore(cfg, rc, log, promMetrics, tracer, luaFiles, algorithmScriptPaths)
 	if err != nil {
-		_ = rc.Close()
+		release()
+		return nil, fmt.Errorf("limiter store: %w", err)
+	}
+	log.Info("Limiter store initialized", ports.Field{Key: "type", Val: cfg.Store.Type})
+
+	// Load scripts into the store and get SHA1 hashes
+	scriptSHA1s, err := loadScriptsIntoRedis(ctx, store, luaFiles, log)
+	if err != nil {
+		release()
 		return nil, fmt.Errorf("load scripts into redis: %w", err)
 	}
 	log.Info("Lua scripts loaded into Redis", ports.Field{Key: "count", Val: len(scriptSHA1s)})
@@ -101,43 +181,120 @@ func New(ctx context.Context, log ports.Logger, cfg *config.Config) (*Container,
 	_ = registry.Register(domainConfig.AlgorithmFixedWindow, limiter.FixedWindowLimiterFactory)
 	_ = registry.Register(domainConfig.AlgorithmTokenBucket, limiter.TokenBucketLimiterFactory)
 	_ = registry.Register(domainConfig.AlgorithmSlidingWindow, limiter.SlidingWindowLimiterFactory)
+	_ = registry.Register(domainConfig.AlgorithmLeakyBucket, limiter.LeakyBucketLimiterFactory)
+	_ = registry.Register(domainConfig.AlgorithmGCRA, limiter.GCRALimiterFactory)
+	_ = registry.Register(domainConfig.AlgorithmSlidingWindowCounter, limiter.SlidingWindowCounterLimiterFactory)
+	_ = registry.Register(domainConfig.AlgorithmConcurrency, limiter.ConcurrencyLimiterFactory)
 
 	// Load limiter algorithms with SHA1 hashes
 	limiters := make(map[string]ports.RateLimiter)
-	for algo, scriptPath := range map[string]string{
-		domainConfig.AlgorithmFixedWindow:   fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmFixedWindow),
-		domainConfig.AlgorithmTokenBucket:   fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmTokenBucket),
-		domainConfig.AlgorithmSlidingWindow: fmt.Sprintf("scripts/lua/%s.lua", domainConfig.AlgorithmSlidingWindow),
-	} {
+	for algo, scriptPath := range algorithmScriptPaths {
 		// Create instance of each registered algorithm with SHA1 hash
-		limiterInstance, err := registry.Create(algo, redisAdapter, scriptSHA1s[scriptPath])
+		limiterInstance, err := registry.Create(algo, store, scriptSHA1s[scriptPath])
 		if err != nil {
-			_ = rc.Close()
+			release()
 			return nil, fmt.Errorf("create limiter '%s': %w", algo, err)
 		}
 		limiters[algo] = limiterInstance
 	}
 
+	multiLimiter := limiter.NewMultiLimiter(store, scriptSHA1s[multiLimitScriptPath], limiters)
+	localLimiter := limiter.NewLocalLimiter()
+	penaltyBox := limiter.NewPenaltyBox(store, scriptSHA1s[penaltyScriptPath])
+
 	log.Info("Rate limiters initialized", ports.Field{Key: "algorithms", Val: registry.GetRegisteredAlgorithms()})
 
-	// Create limiter policy for whitelisted IPs
+	// Create limiter policy for whitelisted IPs and CIDR ranges
 	policy, err := domainLimiter.NewPolicy(cfg.App.WhitelistedIPs)
 	if err != nil {
-		_ = rc.Close()
+		release()
 		return nil, fmt.Errorf("policy creation: %w", err)
 	}
 
 	// Rate limiter service
-	limiterSvc := service.NewLimiterService(log, cfgSvc, limiters, policy)
+	limiterSvc := service.NewLimiterService(log, cfgSvc, limiters, multiLimiter, localLimiter, penaltyBox, policy, promMetrics, tracer)
 	log.Info("LimiterService initialized", ports.Field{Key: "whitelisted_ips", Val: policy.WhitelistedIPsCount()})
 
 	// HTTP
-	h, err := handler.NewHTTPHandler(limiterSvc, log, cfg.App.BackendNginxAddr)
+	trustedProxies, err := domainConfig.ParseIPRanges(cfg.Server.TrustedProxies)
 	if err != nil {
-		_ = rc.Close()
+		release()
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	clientIP, err := handler.NewClientIPResolver(trustedProxies, cfg.Server.ClientIPHeaders)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("client IP resolver: %w", err)
+	}
+
+	rateLimitHeaders, err := handler.NewRateLimitHeaders(cfg.Server.RateLimitHeaders)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("rate limit headers: %w", err)
+	}
+
+	// Upstream pools of routes, rebuilt as their config changes
+	upstreams := upstream.NewManager(ctx, log)
+	go upstreams.Watch(ctx, cfgSvc, upstreamPruneInterval)
+
+	h, err := handler.NewHTTPHandler(limiterSvc, log, promMetrics, tracer, clientIP, rateLimitHeaders, upstreams, cfg.App.BackendNginxAddr)
+	if err != nil {
+		release()
 		return nil, fmt.Errorf("http handler: %w", err)
 	}
-	log.Info("HTTPHandler initialized")
+	upstreams.OnRemove(h.ForgetUpstreams)
+	log.Info("HTTPHandler initialized", ports.Field{Key: "trusted_proxies", Val: len(trustedProxies)})
+
+	var serverHandler http.Handler
+	switch cfg.Server.Mode {
+	case "", config.ServerModeProxy:
+		serverHandler = h
+	case config.ServerModeDecision:
+		serverHandler = handler.NewDecisionHandler(h, cfg.Server.DecisionDenyStatus)
+		log.Info("Decision mode enabled, requests are not proxied")
+	default:
+		release()
+		return nil, fmt.Errorf("unknown server mode %q", cfg.Server.Mode)
+	}
+
+	// Check API, for internal services
+	checkServer := check.NewServer(limiterSvc, log, tracer)
+
+	// gRPC, for Envoy sidecars and internal services
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Port > 0 {
+		var opts []grpc.ServerOption
+		switch {
+		case cfg.GRPC.Token != "":
+			opts = append(opts, grpc.UnaryInterceptor(grpcauth.UnaryServerInterceptor(cfg.GRPC.Token)))
+		case !isLoopback(cfg.GRPC.Address):
+			release()
+			return nil, fmt.Errorf("grpc listener on %q requires a token", cfg.GRPC.Address)
+		}
+		grpcServer = grpc.NewServer(opts...)
+		authv3.RegisterAuthorizationServer(grpcServer, envoy.NewAuthorizationServer(handler.NewDecisionHandler(h, 0)))
+		rlsv3.RegisterRateLimitServiceServer(grpcServer, envoy.NewRateLimitServer(limiterSvc, log, tracer, rateLimitHeaders))
+		check.RegisterServer(grpcServer, checkServer)
+		log.Info("Envoy ext_authz, ratelimit and check services initialized")
+	}
+
+	// Admin
+	adminMux := http.NewServeMux()
+	adminMux.Handle("/metrics", promMetrics.Handler())
+	if cfg.Admin.Token != "" {
+		checkHandler := handler.RequireToken(cfg.Admin.Token, log, checkServer.Handler())
+		adminMux.Handle("/v1/check", checkHandler)
+		adminMux.Handle("/v1/check/", checkHandler)
+
+		var breakerControl ports.CircuitBreakerControl
+		if circuitBreaker != nil {
+			breakerControl = circuitBreaker
+		}
+		adminMux.Handle("/admin/", handler.NewAdminHandler(cfgSvc, limiterSvc, registry, store, penaltyBox, breakerControl, log, cfg.Admin.Token))
+		log.Info("Admin and check APIs initialized")
+	} else {
+		log.Info("Admin and HTTP check APIs disabled, no admin token configured")
+	}
 
 	return &Container{
 		Log:                log,
@@ -146,7 +303,11 @@ func New(ctx context.Context, log ports.Logger, cfg *config.Config) (*Container,
 		ConsulClient:       cc,
 		ConfigService:      cfgSvc,
 		RateLimiterService: limiterSvc,
-		HTTPHandler:        h,
+		HTTPHandler:        serverHandler,
+		AdminHandler:       adminMux,
+		GRPCServer:         grpcServer,
+		TrustedProxies:     trustedProxies,
+		Tracer:             tracer,
 	}, nil
 }
 
@@ -163,6 +324,67 @@ func newRedisClient(cfg config.RedisConfig) (*redis.Client, error) {
 	return client, nil
 }
 
+// newLimiterStore returns the store the limiter scripts run against: Redis behind
+// a circuit breaker, or native implementations of the scripts kept in memory
+// without a circuit breaker.
+func newLimiterStore(cfg *config.Config, rc *redis.Client, log ports.Logger, promMetrics *metrics.PrometheusMetrics, tracer ports.Tracer, luaFiles, algorithmScriptPaths map[string]string) (ports.LimiterScore, *resilience.CircuitBreaker, error) {
+	switch cfg.Store.Type {
+	case "", config.StoreRedis:
+		baseRedisAdapter := redis2.NewRedisAdapter(rc, log, cfg.Redis.OperationTimeoutSeconds)
+		circuitBreaker := resilience.NewCircuitBreaker(
+			cfg.Redis.CircuitBreakerMaxFailures,
+			time.Duration(cfg.Redis.CircuitBreakerTimeoutSeconds)*time.Second,
+		)
+		promMetrics.RegisterCircuitBreaker(circuitBreaker)
+		return redis2.NewResilientRedisAdapter(
+			tracing.NewTracedStore(metrics.NewInstrumentedStore(baseRedisAdapter, promMetrics), tracer, config.StoreRedis),
+			log,
+			circuitBreaker,
+		), circuitBreaker, nil
+	case config.StoreMemory:
+		scripts := make(map[string]string, len(algorithmScriptPaths)+1)
+		for algo, scriptPath := range algorithmScriptPaths {
+			if script, ok := luaFiles[scriptPath]; ok {
+				scripts[algo] = script
+			}
+		}
+		if script, ok := luaFiles[multiLimitScriptPath]; ok {
+			scripts[memory.MultiLimitScript] = script
+		}
+		if script, ok := luaFiles[penaltyScriptPath]; ok {
+			scripts[memory.PenaltyScript] = script
+		}
+		store, err := memory.NewMemoryStore(log, scripts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tracing.NewTracedStore(store, tracer, config.StoreMemory), nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown store type %q", cfg.Store.Type)
+	}
+}
+
+func closeRedis(rc *redis.Client) {
+	if rc != nil {
+		_ = rc.Close()
+	}
+}
+
+func shutdownTracer(tracer *tracing.OtelTracer) {
+	ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	defer cancel()
+	_ = tracer.Shutdown(ctx)
+}
+
+// isLoopback reports whether the listener address only accepts local callers.
+func isLoopback(address string) bool {
+	if address == "localhost" {
+		return true
+	}
+	addr, err := netip.ParseAddr(address)
+	return err == nil && addr.IsLoopback()
+}
+
 func newConsulClient(cfg config.ConsulConfig) (*api.Client, error) {
 	consulCfg := api.DefaultConfig()
 	consulCfg.Address = cfg.Addr
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,6 +15,8 @@ import (
 	"github.com/SilentPlaces/rate_limiter/config"
 	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
 	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/logger"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/proxyprotocol"
+	"google.golang.org/grpc"
 )
 
 const configFilePath = "config/config.yml"
@@ -43,16 +46,60 @@ func main() {
 	defer c.Close()
 
 	addr := fmt.Sprintf("%s:%d", c.Config.Server.Address, c.Config.Server.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error("failed to listen", ports.Field{Key: "addr", Val: addr}, ports.Field{Key: "err", Val: err.Error()})
+		return
+	}
+	if c.Config.Server.ProxyProtocol {
+		listener, err = proxyprotocol.NewListener(listener, c.TrustedProxies)
+		if err != nil {
+			log.Error("failed to enable the PROXY protocol", ports.Field{Key: "err", Val: err.Error()})
+			return
+		}
+	}
+
 	server := &http.Server{Addr: addr, Handler: c.HTTPHandler}
-	errCh := make(chan error, 1)
+	errCh := make(chan error, 3)
 
 	go func() {
-		log.Info("HTTP server starting on " + addr)
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Info("HTTP server starting on "+addr, ports.Field{Key: "proxy_protocol", Val: c.Config.Server.ProxyProtocol})
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 		}
 	}()
 
+	// Admin listener, serving metrics apart from proxied traffic
+	var adminServer *http.Server
+	if c.Config.Admin.Port > 0 {
+		adminAddr := fmt.Sprintf("%s:%d", c.Config.Admin.Address, c.Config.Admin.Port)
+		adminServer = &http.Server{Addr: adminAddr, Handler: c.AdminHandler}
+
+		go func() {
+			log.Info("Admin server starting on " + adminAddr)
+			if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
+	// gRPC listener, serving Envoy ext_authz and ratelimit calls
+	if c.GRPCServer != nil {
+		grpcAddr := fmt.Sprintf("%s:%d", c.Config.GRPC.Address, c.Config.GRPC.Port)
+		grpcListener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			log.Error("failed to listen", ports.Field{Key: "addr", Val: grpcAddr}, ports.Field{Key: "err", Val: err.Error()})
+			return
+		}
+
+		go func() {
+			log.Info("gRPC server starting on " + grpcAddr)
+			if err := c.GRPCServer.Serve(grpcListener); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
 	select {
 	case <-sigs:
 		log.Info("Received shutdown signal")
@@ -66,6 +113,12 @@ func main() {
 	}
 
 	shutdownServer(server, log, time.Duration(c.Config.Server.ShutdownTimeoutSeconds)*time.Second)
+	if adminServer != nil {
+		shutdownServer(adminServer, log, time.Duration(c.Config.Server.ShutdownTimeoutSeconds)*time.Second)
+	}
+	if c.GRPCServer != nil {
+		shutdownGRPCServer(c.GRPCServer, log, time.Duration(c.Config.Server.ShutdownTimeoutSeconds)*time.Second)
+	}
 }
 
 func setupSignalHandler() chan os.Signal {
@@ -85,3 +138,22 @@ func shutdownServer(server *http.Server, log ports.Logger, timeout time.Duration
 		log.Info("Server shutdown completed successfully")
 	}
 }
+
+// shutdownGRPCServer waits for in-flight calls to complete, stopping the server
+// outright once timeout has elapsed.
+func shutdownGRPCServer(server *grpc.Server, log ports.Logger, timeout time.Duration) {
+	log.Info("Shutting down gRPC server gracefully...")
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.Info("gRPC server shutdown completed successfully")
+	case <-time.After(timeout):
+		server.Stop()
+		log.Error("gRPC server shutdown timed out, stopped")
+	}
+}
@@ -12,11 +12,40 @@ import (
 
 var K = koanf.New(".")
 
+// Limiter store types
+const (
+	StoreRedis  = "redis"
+	StoreMemory = "memory"
+)
+
+// Server modes
+const (
+	ServerModeProxy    = "proxy"
+	ServerModeDecision = "decision"
+)
+
+// Tracing exporters
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
 type Config struct {
-	Redis  RedisConfig      `koanf:"redis"`
-	Consul ConsulConfig     `koanf:"consul"`
-	Server ServerConfig     `koanf:"server"`
-	App    LimiterAppConfig `koanf:"app"`
+	Store   StoreConfig      `koanf:"store"`
+	Redis   RedisConfig      `koanf:"redis"`
+	Consul  ConsulConfig     `koanf:"consul"`
+	Server  ServerConfig     `koanf:"server"`
+	Admin   AdminConfig      `koanf:"admin"`
+	GRPC    GRPCConfig       `koanf:"grpc"`
+	Tracing TracingConfig    `koanf:"tracing"`
+	App     LimiterAppConfig `koanf:"app"`
+}
+
+// StoreConfig selects where limiter state is kept: StoreRedis (default) shares
+// it between instances, StoreMemory keeps it in process for single instances.
+type StoreConfig struct {
+	Type string `koanf:"type"`
 }
 
 type RedisConfig struct {
@@ -33,10 +62,57 @@ type ConsulConfig struct {
 	Addr string `koanf:"addr"`
 }
 
+// ServerConfig is the listener of proxied traffic. The client IP headers of
+// ClientIPHeaders, X-Forwarded-For by default, are only believed from the
+// addresses and CIDR ranges of TrustedProxies. ProxyProtocol requires the PROXY
+// protocol header from trusted proxies, and refuses it from other peers.
+// RateLimitHeaders selects the rate limit header styles of responses, "legacy"
+// X-RateLimit-* headers by default, or "ietf" RateLimit and RateLimit-Policy
+// headers. Mode is ServerModeProxy (default), proxying allowed requests to the
+// backend, or ServerModeDecision, only answering whether requests are allowed
+// for forward auth proxies, rate limited requests getting DecisionDenyStatus
+// when set.
 type ServerConfig struct {
-	Port                   int    `koanf:"port"`
-	Address                string `koanf:"address"`
-	ShutdownTimeoutSeconds int    `koanf:"shutdown_timeout_seconds"`
+	Port                   int      `koanf:"port"`
+	Address                string   `koanf:"address"`
+	ShutdownTimeoutSeconds int      `koanf:"shutdown_timeout_seconds"`
+	TrustedProxies         []string `koanf:"trusted_proxies"`
+	ClientIPHeaders        []string `koanf:"client_ip_headers"`
+	ProxyProtocol          bool     `koanf:"proxy_protocol"`
+	RateLimitHeaders       []string `koanf:"rate_limit_headers"`
+	Mode                   string   `koanf:"mode"`
+	DecisionDenyStatus     int      `koanf:"decision_deny_status"`
+}
+
+// AdminConfig is the listener serving operational endpoints such as /metrics
+// and the JSON check API, kept apart from proxied traffic. It is disabled when
+// Port is zero. The admin API is served under /admin/ to requests bearing Token,
+// and disabled without it.
+type AdminConfig struct {
+	Port    int    `koanf:"port"`
+	Address string `koanf:"address"`
+	Token   string `koanf:"token"`
+}
+
+// GRPCConfig is the listener of the Envoy ext_authz and ratelimit gRPC services,
+// for Envoy sidecars calling the rate limiter directly, and of the check API of
+// internal services. It is disabled when Port is zero. Callers must send Token
+// as a bearer token, which may only be left empty on a loopback Address.
+type GRPCConfig struct {
+	Port    int    `koanf:"port"`
+	Address string `koanf:"address"`
+	Token   string `koanf:"token"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported: nowhere
+// (TracingExporterNone, default), to standard output or to an OTLP/HTTP collector.
+// SampleRatio is the share of new traces recorded, every trace when unset.
+type TracingConfig struct {
+	Exporter    string   `koanf:"exporter"`
+	Endpoint    string   `koanf:"endpoint"`
+	Insecure    bool     `koanf:"insecure"`
+	ServiceName string   `koanf:"service_name"`
+	SampleRatio *float64 `koanf:"sample_ratio"`
 }
 
 type LimiterAppConfig struct {
@@ -66,9 +142,13 @@ func LoadConfig(path string, logger lgr.Logger) (*Config, error) {
 
 	logger.Info(
 		"Configuration loaded",
+		lgr.Field{Key: "store", Val: cfg.Store},
 		lgr.Field{Key: "redis", Val: cfg.Redis},
 		lgr.Field{Key: "consul", Val: cfg.Consul},
 		lgr.Field{Key: "server", Val: cfg.Server},
+		lgr.Field{Key: "admin", Val: map[string]interface{}{"port": cfg.Admin.Port, "address": cfg.Admin.Address, "token_set": cfg.Admin.Token != ""}},
+		lgr.Field{Key: "grpc", Val: map[string]interface{}{"port": cfg.GRPC.Port, "address": cfg.GRPC.Address, "token_set": cfg.GRPC.Token != ""}},
+		lgr.Field{Key: "tracing", Val: cfg.Tracing},
 		lgr.Field{Key: "app", Val: cfg.App},
 	)
 	return &cfg, nil
@@ -0,0 +1,16 @@
+package ports
+
+// CircuitBreakerControl inspects and overrides the circuit breaker protecting
+// the limiter store.
+type CircuitBreakerControl interface {
+	Status() CircuitBreakerStatus
+	// SetOverride forces the breaker "open" or "closed", or clears the override when empty.
+	SetOverride(state string) error
+}
+
+type CircuitBreakerStatus struct {
+	State    string
+	Failures int
+	// Override is the forced state, empty when the breaker changes state automatically.
+	Override string
+}
@@ -0,0 +1,9 @@
+package ports
+
+import "context"
+
+// Lease is capacity held by a request for as long as it is in flight.
+// It must be released once the request completes.
+type Lease interface {
+	Release(ctx context.Context) error
+}
@@ -1,6 +1,18 @@
 package ports
 
-import "context"
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Key types reported by KeyState
+const (
+	KeyTypeNone      = "none"
+	KeyTypeString    = "string"
+	KeyTypeHash      = "hash"
+	KeyTypeSortedSet = "zset"
+)
 
 type LimiterScore interface {
 	Set(ctx context.Context, key string, value interface{}, ttlSeconds int) error
@@ -9,4 +21,34 @@ type LimiterScore interface {
 	Eval(ctx context.Context, script string, keys []string, args ...[]interface{}) (interface{}, error)
 	ScriptLoad(ctx context.Context, script string) (string, error)
 	EvalSha(ctx context.Context, sha1 string, keys []string, args ...[]interface{}) (interface{}, error)
+	// Inspect returns the state kept under key, with KeyTypeNone when it does not exist.
+	Inspect(ctx context.Context, key string) (KeyState, error)
+	// Keys returns the keys matching a Redis glob pattern.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+	// Delete removes keys, returning how many existed.
+	Delete(ctx context.Context, keys ...string) (int64, error)
+}
+
+// KeyState is the raw limiter state kept under a key: a counter or timestamp,
+// a hash such as the tokens of a bucket, or a sorted set of requests or leases.
+type KeyState struct {
+	Key  string
+	Type string
+	// TTL is the remaining time to live, or -1 when the key does not expire.
+	TTL     time.Duration
+	Value   string
+	Fields  map[string]string
+	Members []ScoredMember
+}
+
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// EscapePattern escapes the glob characters of s for LimiterScore.Keys.
+func EscapePattern(s string) string {
+	return patternEscaper.Replace(s)
 }
+
+var patternEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
@@ -0,0 +1,8 @@
+package ports
+
+// LocalRateLimiter enforces an approximation of limits in process, without the
+// shared limiter store. Each limit is scaled down to fraction of its quota, and
+// either all limits consume cost or none do.
+type LocalRateLimiter interface {
+	AllowAll(checks []LimitCheck, fraction float64) []RateLimitInfo
+}
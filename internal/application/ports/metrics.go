@@ -0,0 +1,23 @@
+package ports
+
+import "time"
+
+// Metrics records the operational metrics of the rate limiter. Route labels
+// must come from the configuration rather than from clients, to keep the
+// number of series bounded.
+type Metrics interface {
+	// RecordDecision counts a request allowed or denied by a limit of route.
+	RecordDecision(route, algorithm string, allowed bool)
+	// RecordWhitelistBypass counts a request of route bypassing rate limiting.
+	RecordWhitelistBypass(route string)
+	// RecordDenylistBlock counts a request of route refused by a deny list.
+	RecordDenylistBlock(route string)
+	// RecordFailureMode counts a request of route decided by a failure mode.
+	RecordFailureMode(route, mode string)
+	// RecordConfigReload counts a configuration reload from the provider.
+	RecordConfigReload(success bool)
+	// ObserveStoreLatency records the duration of a limiter store operation.
+	ObserveStoreLatency(operation string, duration time.Duration, err error)
+	// ObserveUpstreamLatency records the duration of a proxied request of route.
+	ObserveUpstreamLatency(route string, status int, duration time.Duration)
+}
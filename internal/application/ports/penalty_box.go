@@ -0,0 +1,31 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// PenaltyBox bans clients of a route whose requests are rejected too often.
+type PenaltyBox interface {
+	// Check returns the ban of the client on route, if any.
+	Check(ctx context.Context, route, clientKey string) (Ban, bool, error)
+	// RecordRejection counts a rejection of the client on route, returning the
+	// ban started once the penalty threshold is reached.
+	RecordRejection(ctx context.Context, route, clientKey string, penalty config.PenaltyConfig) (Ban, bool, error)
+	// List returns the clients currently banned on route.
+	List(ctx context.Context, route string) ([]Ban, error)
+	// Remove lifts the ban of the client on route and forgets its past bans.
+	Remove(ctx context.Context, route, clientKey string) (bool, error)
+}
+
+type Ban struct {
+	Route     string
+	ClientKey string
+	// Remaining is how long the ban still lasts.
+	Remaining time.Duration
+	// Strike counts the bans of the client, each longer than the previous one
+	// when the penalty grows.
+	Strike int
+}
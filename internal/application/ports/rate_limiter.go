@@ -7,5 +7,6 @@ import (
 )
 
 type RateLimiter interface {
-	Allow(ctx context.Context, key string, cfg config.AlgorithmConfig) (RateLimitInfo, error)
+	// Allow consumes cost units of the limit for key.
+	Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (RateLimitInfo, error)
 }
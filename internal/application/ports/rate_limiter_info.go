@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
 )
@@ -15,4 +16,30 @@ type RateLimitInfo struct {
 	Limit     int
 	Remaining int
 	ResetTime int64
+	// Delay is how long an allowed request must be held before it is forwarded.
+	Delay time.Duration
+	// Lease is set by limiters that track in-flight requests and is nil otherwise.
+	Lease Lease
+	// RetryAfter is how long a rejected client should wait, when known.
+	RetryAfter time.Duration
+	// FailureMode is set when the limiter store was unavailable and the route
+	// failure mode decided the request.
+	FailureMode string
+	// RoutePolicy tells whether the route's own limits or an unknown route
+	// policy applied; it is empty for whitelisted and blocked clients.
+	RoutePolicy string
+	// Banned is set when the client is in the penalty box of the route.
+	Banned bool
+	// Blocked is set when the client IP is in a deny list.
+	Blocked bool
+	// Policy names the limit Limit, Remaining and ResetTime report, among Policies.
+	Policy string
+	// Policies are the limits of the route, advertised in the RateLimit-Policy header.
+	Policies []LimitPolicy
+}
+
+// LimitPolicy is a limit of a route as advertised to clients.
+type LimitPolicy struct {
+	Name  string
+	Quota config.Quota
 }
@@ -0,0 +1,40 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// ScriptCall is a single invocation of a limiter Lua script. The script receives
+// a trailing dry-run flag after Args, appended by whoever evaluates the call.
+type ScriptCall struct {
+	Keys []string
+	Args []interface{}
+}
+
+// ScriptedRateLimiter is a RateLimiter backed by a Lua script, which lets several
+// limits be evaluated together inside one atomic script.
+type ScriptedRateLimiter interface {
+	RateLimiter
+	BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ScriptCall, error)
+	ParseResult(call ScriptCall, res interface{}, cfg config.AlgorithmConfig) (RateLimitInfo, error)
+}
+
+// LimitCheck is one limit of a stacked evaluation.
+type LimitCheck struct {
+	Algorithm string
+	Key       string
+	Cost      int
+	Config    config.AlgorithmConfig
+	// Policy names the limit in the RateLimit headers.
+	Policy string
+}
+
+// MultiRateLimiter evaluates several limits atomically: either all of them
+// consume cost, or none do.
+type MultiRateLimiter interface {
+	AllowAll(ctx context.Context, checks []LimitCheck) ([]RateLimitInfo, error)
+	// CheckAll evaluates the limits without consuming anything.
+	CheckAll(ctx context.Context, checks []LimitCheck) ([]RateLimitInfo, error)
+}
@@ -0,0 +1,34 @@
+package ports
+
+import (
+	"context"
+	"net/http"
+)
+
+// SpanKind describes the relationship of a span to its parent and children.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota
+	SpanKindServer
+	SpanKindClient
+)
+
+// Tracer records spans of the request lifecycle and propagates the trace
+// context across process boundaries through HTTP headers.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any. The returned
+	// context holds the new span and must be passed to nested operations.
+	Start(ctx context.Context, name string, kind SpanKind, attributes ...Field) (context.Context, Span)
+	// Extract returns ctx with the remote trace context found in header.
+	Extract(ctx context.Context, header http.Header) context.Context
+	// Inject writes the trace context of ctx to header.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a single operation within a trace.
+type Span interface {
+	SetAttributes(attributes ...Field)
+	RecordError(err error)
+	End()
+}
@@ -0,0 +1,15 @@
+package ports
+
+import (
+	"net/url"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// Upstreams balances allowed requests over the upstream pools of routes.
+type Upstreams interface {
+	// Pick returns the target of the next request of route, from the pool
+	// described by upstream, and a func to call once the request completes.
+	// It returns false when upstream has no targets.
+	Pick(route string, upstream config.UpstreamConfig) (*url.URL, func(), bool)
+}
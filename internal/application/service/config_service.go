@@ -15,17 +15,20 @@ type ConfigService struct {
 	logger    ports.Logger
 	config    atomic.Value // config.Config
 	appConfig appConfig.LimiterAppConfig
+	metrics   ports.Metrics
 }
 
 func NewConfigService(
 	provider ports.ConfigProvider,
 	logger ports.Logger,
 	cfg appConfig.LimiterAppConfig,
+	metrics ports.Metrics,
 ) *ConfigService {
 	cs := &ConfigService{
 		provider:  provider,
 		logger:    logger,
 		appConfig: cfg,
+		metrics:   metrics,
 	}
 	cs.config.Store(config.Config{Routes: make(map[string]config.RouteConfig)})
 	return cs
@@ -56,11 +59,13 @@ func (c *ConfigService) WatchConfig(ctx context.Context, key string) {
 
 func (c *ConfigService) handleConfigUpdate(cfg config.Config) {
 	c.config.Store(cfg)
+	c.metrics.RecordConfigReload(true)
 	c.logger.Info("ConfigService: handleConfigUpdate: Config updated via provider")
 }
 
 func (c *ConfigService) handleConfigError(err error) {
 	if err != nil {
+		c.metrics.RecordConfigReload(false)
 		c.logger.Error("ConfigService: handleConfigError: Watch config error", ports.Field{Key: "err", Val: err})
 	}
 }
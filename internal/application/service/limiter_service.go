@@ -3,89 +3,612 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
 	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
 	"github.com/SilentPlaces/rate_limiter/internal/domain/limiter"
 )
 
-const rateLimitKeyPrefix = "rl:%s:%s:%s"
+const (
+	// Limiter keys hash tag the route and client, so every key of a client,
+	// including the window keys of an algorithm and the keys of stacked limits,
+	// lands in the same Redis Cluster slot for the scripts using several keys.
+	rateLimitKeyPrefix        = "rl:%s:{%s:%s}"
+	stackedRateLimitKeyPrefix = "rl:%s:{%s:%s}:%s"
+
+	// unknownRouteLabel and noAlgorithmLabel label metrics of requests to unknown
+	// routes, whose names come from clients and must not become metric labels.
+	unknownRouteLabel = "unknown"
+	noAlgorithmLabel  = "none"
+	// penaltyLabel labels metrics of requests rejected by the penalty box.
+	penaltyLabel = "penalty"
+)
 
 type LimiterService struct {
 	logger        ports.Logger
 	configService ports.ConfigService
 	limiters      map[string]ports.RateLimiter
+	multiLimiter  ports.MultiRateLimiter
+	localLimiter  ports.LocalRateLimiter
+	penaltyBox    ports.PenaltyBox
 	policy        *limiter.Policy
+	metrics       ports.Metrics
+	tracer        ports.Tracer
 }
 
 func NewLimiterService(
 	logger ports.Logger,
 	configService ports.ConfigService,
 	limiters map[string]ports.RateLimiter,
+	multiLimiter ports.MultiRateLimiter,
+	localLimiter ports.LocalRateLimiter,
+	penaltyBox ports.PenaltyBox,
 	policy *limiter.Policy,
+	metrics ports.Metrics,
+	tracer ports.Tracer,
 ) *LimiterService {
 	return &LimiterService{
 		logger:        logger,
 		configService: configService,
 		limiters:      limiters,
+		multiLimiter:  multiLimiter,
+		localLimiter:  localLimiter,
+		penaltyBox:    penaltyBox,
 		policy:        policy,
+		metrics:       metrics,
+		tracer:        tracer,
+	}
+}
+
+// Route returns the configuration applied to the given route, which is the
+// default route for unknown routes when the unknown route policy selects it.
+func (l *LimiterService) Route(route string) (config.RouteConfig, bool) {
+	routeConfig, _, ok := l.configService.GetConfig().Lookup(route)
+	return routeConfig, ok
+}
+
+// RouteLabel returns the name route is reported under in metrics: the route
+// itself when configured, the default route when it applies, and
+// unknownRouteLabel otherwise.
+func (l *LimiterService) RouteLabel(route string) string {
+	_, policy, ok := l.configService.GetConfig().Lookup(route)
+	return routeLabel(route, policy, ok)
+}
+
+// RouteWithLabel returns the config of route, as Route, and its RouteLabel from
+// a single read of the config.
+func (l *LimiterService) RouteWithLabel(route string) (config.RouteConfig, string) {
+	routeConfig, policy, ok := l.configService.GetConfig().Lookup(route)
+	return routeConfig, routeLabel(route, policy, ok)
+}
+
+func routeLabel(route, policy string, ok bool) string {
+	switch {
+	case !ok:
+		return unknownRouteLabel
+	case policy == config.UnknownRouteDefault:
+		return config.DefaultRoute
+	default:
+		return route
+	}
+}
+
+// MatchRoute returns the route whose matchers select the request, if any.
+func (l *LimiterService) MatchRoute(method, host, path string) (string, bool) {
+	return l.configService.GetConfig().MatchRoute(method, host, path)
+}
+
+// AllowWithInfo checks the rate limit of route for the client, consuming cost units.
+// Clients are keyed by clientKey, as extracted by the route key definition, or by
+// ip when it is empty. When the route stacks several limits, all of them must pass
+// and the most restrictive one is reported.
+// The cost is clamped to the cost bounds of the route.
+func (l *LimiterService) AllowWithInfo(ctx context.Context, ip, route, clientKey string, cost int) (ports.RateLimitInfo, error) {
+	return l.decide(ctx, "LimiterService.AllowWithInfo", ip, route, clientKey, cost, modeAllow)
+}
+
+// ReserveWithInfo decides the request as AllowWithInfo, but returns allowed
+// requests queued by a leaky bucket right away, leaving their Delay to the caller
+// to wait out.
+func (l *LimiterService) ReserveWithInfo(ctx context.Context, ip, route, clientKey string, cost int) (ports.RateLimitInfo, error) {
+	return l.decide(ctx, "LimiterService.ReserveWithInfo", ip, route, clientKey, cost, modeReserve)
+}
+
+// CheckWithInfo reports whether a request of the default cost would be allowed
+// on route for the client, without consuming anything. Allowed requests get
+// neither leases nor delays, and rejections don't count towards the penalty box.
+func (l *LimiterService) CheckWithInfo(ctx context.Context, ip, route, clientKey string) (ports.RateLimitInfo, error) {
+	return l.decide(ctx, "LimiterService.CheckWithInfo", ip, route, clientKey, config.DefaultCost, modeCheck)
+}
+
+// decideMode is how decide treats the limits and the queue delay of a request.
+type decideMode int
+
+const (
+	// modeAllow consumes the cost, holding allowed requests for their delay.
+	modeAllow decideMode = iota
+	// modeReserve consumes the cost, returning the delay to the caller.
+	modeReserve
+	// modeCheck consumes nothing.
+	modeCheck
+)
+
+func (l *LimiterService) decide(ctx context.Context, name, ip, route, clientKey string, cost int, mode decideMode) (ports.RateLimitInfo, error) {
+	ctx, span := l.tracer.Start(ctx, name, ports.SpanKindInternal,
+		ports.Field{Key: "rate_limiter.route", Val: route},
+		ports.Field{Key: "rate_limiter.cost", Val: cost})
+	defer span.End()
+
+	info, err := l.allow(ctx, span, ip, route, clientKey, cost, mode)
+	if err != nil {
+		span.RecordError(err)
+		return info, err
+	}
+
+	decision := "denied"
+	if info.Allowed {
+		decision = "allowed"
 	}
+	span.SetAttributes(
+		ports.Field{Key: "rate_limiter.decision", Val: decision},
+		ports.Field{Key: "rate_limiter.limit", Val: info.Limit},
+		ports.Field{Key: "rate_limiter.remaining", Val: info.Remaining})
+	if info.RoutePolicy != "" {
+		span.SetAttributes(ports.Field{Key: "rate_limiter.route_policy", Val: info.RoutePolicy})
+	}
+	if info.FailureMode != "" {
+		span.SetAttributes(ports.Field{Key: "rate_limiter.failure_mode", Val: info.FailureMode})
+	}
+	return info, nil
 }
 
-func (l *LimiterService) AllowWithInfo(ctx context.Context, ip, route string) (ports.RateLimitInfo, error) {
-	if l.policy.ShouldBypassRateLimit(ip) {
+// allow decides the request with mode, annotating span with how it was decided.
+func (l *LimiterService) allow(ctx context.Context, span ports.Span, ip, route, clientKey string, cost int, mode decideMode) (ports.RateLimitInfo, error) {
+	cfg := l.configService.GetConfig()
+	routeConfig, policy, ok := cfg.Lookup(route)
+
+	switch l.policy.Decide(ip, cfg.Access, routeConfig.Access) {
+	case limiter.AccessDeny:
+		l.logger.Info("LimiterService: Allow: IP denied, blocking request",
+			ports.Field{Key: "event", Val: "client_blocked"},
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "route", Val: route})
+		l.metrics.RecordDenylistBlock(l.RouteLabel(route))
+		span.SetAttributes(ports.Field{Key: "rate_limiter.blocked", Val: true})
+		return ports.RateLimitInfo{Allowed: false, Limit: -1, Remaining: -1, ResetTime: 0, Blocked: true}, nil
+	case limiter.AccessBypass:
 		l.logger.Info("LimiterService: Allow: IP whitelisted, bypassing rate limit",
 			ports.Field{Key: "ip", Val: ip},
 			ports.Field{Key: "route", Val: route})
+		l.metrics.RecordWhitelistBypass(l.RouteLabel(route))
+		span.SetAttributes(ports.Field{Key: "rate_limiter.whitelisted", Val: true})
 		return ports.RateLimitInfo{Allowed: true, Limit: -1, Remaining: -1, ResetTime: 0}, nil
 	}
 
-	cfg := l.configService.GetConfig()
-
-	routeConfig, ok := cfg.Routes[route]
-	if !ok {
-		l.logger.Info("LimiterService: Allow: route not found",
+	switch {
+	case !ok && policy == config.UnknownRouteReject:
+		l.logger.Info("LimiterService: Allow: route not found, rejecting request",
 			ports.Field{Key: "route", Val: route},
-			ports.Field{Key: "ip", Val: ip})
-		return ports.RateLimitInfo{Allowed: true, Limit: -1, Remaining: -1, ResetTime: 0}, nil
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "policy", Val: policy})
+		l.metrics.RecordDecision(unknownRouteLabel, noAlgorithmLabel, false)
+		return ports.RateLimitInfo{Allowed: false, Limit: -1, Remaining: -1, ResetTime: 0, RoutePolicy: policy}, nil
+	case !ok:
+		l.logger.Info("LimiterService: Allow: route not found, allowing request unlimited",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "policy", Val: policy})
+		l.metrics.RecordDecision(unknownRouteLabel, noAlgorithmLabel, true)
+		return ports.RateLimitInfo{Allowed: true, Limit: -1, Remaining: -1, ResetTime: 0, RoutePolicy: policy}, nil
+	case policy == config.UnknownRouteDefault:
+		l.logger.Info("LimiterService: Allow: route not found, applying default route",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "policy", Val: policy})
+		route = config.DefaultRoute
+		span.SetAttributes(ports.Field{Key: "rate_limiter.route", Val: route})
 	}
 
-	limiter, ok := l.limiters[routeConfig.Algorithm]
-	if !ok {
-		l.logger.Error("LimiterService: Allow: limiter not found for algorithm",
-			ports.Field{Key: "algorithm", Val: routeConfig.Algorithm},
+	if len(routeConfig.Limits) == 0 {
+		l.logger.Error("LimiterService: Allow: route has no limits",
 			ports.Field{Key: "route", Val: route})
-		return ports.RateLimitInfo{}, errors.NewRateLimiterError(
-			"UNKNOWN_ALGORITHM",
-			fmt.Sprintf("algorithm '%s' not found", routeConfig.Algorithm),
-			nil,
-		)
+		return ports.RateLimitInfo{}, errors.NewRateLimiterError("INVALID_CONFIG",
+			fmt.Sprintf("route '%s' has no limits", route), nil)
 	}
 
-	if err := routeConfig.Config.Validate(); err != nil {
-		l.logger.Error("LimiterService: Allow: invalid configuration",
-			ports.Field{Key: "algorithm", Val: routeConfig.Algorithm},
+	if err := routeConfig.Cost.Validate(); err != nil {
+		l.logger.Error("LimiterService: Allow: invalid cost configuration",
 			ports.Field{Key: "route", Val: route},
 			ports.Field{Key: "error", Val: err})
 		return ports.RateLimitInfo{}, errors.NewRateLimiterError("INVALID_CONFIG", err.Error(), err)
 	}
 
-	key := l.buildRateLimitKey(routeConfig.Algorithm, route, ip)
+	cost = routeConfig.Cost.Clamp(cost)
 
-	l.logger.Info("LimiterService: Allow: checking rate limit",
-		ports.Field{Key: "key", Val: key},
-		ports.Field{Key: "route", Val: route},
-		ports.Field{Key: "ip", Val: ip},
-		ports.Field{Key: "algorithm", Val: routeConfig.Algorithm})
+	// Access lists apply to the address of the client, its IP prefix only
+	// aggregating the clients sharing its limits
+	if clientKey == "" {
+		clientKey = routeConfig.IPPrefix.Apply(ip)
+	}
 
-	info, err := limiter.Allow(ctx, key, routeConfig.Config)
-	if err != nil {
+	if routeConfig.Penalty.Enabled() {
+		if info, banned := l.checkPenalty(ctx, route, clientKey); banned {
+			info.RoutePolicy = policy
+			span.SetAttributes(ports.Field{Key: "rate_limiter.banned", Val: true})
+			return info, nil
+		}
+	}
+
+	checks := make([]ports.LimitCheck, 0, len(routeConfig.Limits))
+	for _, limit := range routeConfig.Limits {
+		if _, ok := l.limiters[limit.Algorithm]; !ok {
+			l.logger.Error("LimiterService: Allow: limiter not found for algorithm",
+				ports.Field{Key: "algorithm", Val: limit.Algorithm},
+				ports.Field{Key: "route", Val: route})
+			return ports.RateLimitInfo{}, errors.NewRateLimiterError(
+				"UNKNOWN_ALGORITHM",
+				fmt.Sprintf("algorithm '%s' not found", limit.Algorithm),
+				nil,
+			)
+		}
+
+		if err := limit.Config.Validate(); err != nil {
+			l.logger.Error("LimiterService: Allow: invalid configuration",
+				ports.Field{Key: "algorithm", Val: limit.Algorithm},
+				ports.Field{Key: "route", Val: route},
+				ports.Field{Key: "limit", Val: limit.Name},
+				ports.Field{Key: "error", Val: err})
+			return ports.RateLimitInfo{}, errors.NewRateLimiterError("INVALID_CONFIG", err.Error(), err)
+		}
+
+		key := l.buildRateLimitKey(limit, route, clientKey, len(routeConfig.Limits) > 1)
+
+		l.logger.Info("LimiterService: Allow: checking rate limit",
+			ports.Field{Key: "key", Val: key},
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "client_key", Val: clientKey},
+			ports.Field{Key: "algorithm", Val: limit.Algorithm},
+			ports.Field{Key: "cost", Val: cost})
+
+		checks = append(checks, ports.LimitCheck{
+			Algorithm: limit.Algorithm,
+			Key:       key,
+			Cost:      cost,
+			Config:    limit.Config,
+			Policy:    policyName(limit, route, len(routeConfig.Limits) > 1),
+		})
+	}
+
+	algorithms := make([]string, len(checks))
+	for i, check := range checks {
+		algorithms[i] = check.Algorithm
+	}
+	span.SetAttributes(ports.Field{Key: "rate_limiter.algorithm", Val: algorithms})
+
+	var info ports.RateLimitInfo
+	infos, err := l.evaluate(ctx, checks, mode == modeCheck)
+	switch {
+	case err == nil:
+		info = mostRestrictive(withPolicies(checks, infos))
+	case ctx.Err() == nil && errors.IsStoreUnavailable(err):
+		info, infos = l.onStoreFailure(route, cfg.FailureFor(routeConfig), checks, err)
+	default:
 		return ports.RateLimitInfo{}, err
 	}
+	info.RoutePolicy = policy
+	info.Policies = limitPolicies(checks)
+	l.recordDecisions(route, checks, infos, info.Allowed)
+
+	if mode == modeCheck {
+		info.Delay = 0
+		return info, nil
+	}
+
+	if !info.Allowed && info.FailureMode == "" && routeConfig.Penalty.Enabled() {
+		l.recordRejection(ctx, route, clientKey, routeConfig.Penalty, &info)
+	}
+
+	if mode == modeAllow && info.Allowed && info.Delay > 0 {
+		if err := waitForDelay(ctx, info.Delay); err != nil {
+			l.releaseLease(info.Lease, route)
+			return ports.RateLimitInfo{}, err
+		}
+	}
 
 	return info, nil
 }
 
-func (l *LimiterService) buildRateLimitKey(algorithm, route, ip string) string {
-	return fmt.Sprintf(rateLimitKeyPrefix, algorithm, route, ip)
+// onStoreFailure decides the request with the route failure mode when the
+// limiter store is unavailable, e.g. while the Redis circuit breaker is open.
+// Other evaluation errors, such as a missing script, fail the request instead.
+// The results of each limit are only returned by the local failure mode.
+func (l *LimiterService) onStoreFailure(route string, failure config.FailureConfig, checks []ports.LimitCheck, err error) (ports.RateLimitInfo, []ports.RateLimitInfo) {
+	l.logger.Error("LimiterService: Allow: limiter store unavailable, applying failure mode",
+		ports.Field{Key: "route", Val: route},
+		ports.Field{Key: "failure_mode", Val: failure.Mode},
+		ports.Field{Key: "error", Val: err})
+
+	var (
+		info  ports.RateLimitInfo
+		infos []ports.RateLimitInfo
+	)
+	switch failure.Mode {
+	case config.FailureModeClosed:
+		retryAfter := time.Duration(failure.RetryAfter) * time.Second
+		info = ports.RateLimitInfo{
+			Allowed:    false,
+			Limit:      -1,
+			Remaining:  -1,
+			ResetTime:  time.Now().Add(retryAfter).Unix(),
+			RetryAfter: retryAfter,
+		}
+	case config.FailureModeLocal:
+		infos = withPolicies(checks, l.localLimiter.AllowAll(checks, failure.LocalFraction))
+		info = mostRestrictive(infos)
+	default:
+		info = ports.RateLimitInfo{Allowed: true, Limit: -1, Remaining: -1, ResetTime: 0}
+	}
+
+	info.FailureMode = failure.Mode
+	l.metrics.RecordFailureMode(route, failure.Mode)
+	return info, infos
+}
+
+// checkPenalty rejects clients in the penalty box of route. Bans can't be
+// checked while the limiter store is unavailable, so the route limits then apply.
+func (l *LimiterService) checkPenalty(ctx context.Context, route, clientKey string) (ports.RateLimitInfo, bool) {
+	ban, banned, err := l.penaltyBox.Check(ctx, route, clientKey)
+	if err != nil {
+		// Logged at debug level, as every request fails while the store is down
+		// and the evaluation of the limits reports the outage
+		l.logger.Debug("LimiterService: Allow: failed to check penalty box",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "error", Val: err})
+		return ports.RateLimitInfo{}, false
+	}
+	if !banned {
+		return ports.RateLimitInfo{}, false
+	}
+
+	l.logger.Debug("LimiterService: Allow: client banned, rejecting request",
+		ports.Field{Key: "route", Val: route},
+		ports.Field{Key: "client_key", Val: clientKey},
+		ports.Field{Key: "remaining", Val: ban.Remaining.String()})
+	l.metrics.RecordDecision(route, penaltyLabel, false)
+
+	return ports.RateLimitInfo{
+		Allowed:    false,
+		Limit:      -1,
+		Remaining:  0,
+		ResetTime:  time.Now().Add(ban.Remaining).Unix(),
+		RetryAfter: ban.Remaining,
+		Banned:     true,
+	}, true
+}
+
+// recordRejection counts a rejection towards the penalty of the client, banning
+// it once the route threshold is reached.
+func (l *LimiterService) recordRejection(ctx context.Context, route, clientKey string, penalty config.PenaltyConfig, info *ports.RateLimitInfo) {
+	ban, started, err := l.penaltyBox.RecordRejection(ctx, route, clientKey, penalty)
+	if err != nil {
+		l.logger.Error("LimiterService: Allow: failed to record rejection in penalty box",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "error", Val: err})
+		return
+	}
+	if !started {
+		return
+	}
+
+	l.logger.Info("LimiterService: Allow: client banned",
+		ports.Field{Key: "event", Val: "client_banned"},
+		ports.Field{Key: "route", Val: route},
+		ports.Field{Key: "client_key", Val: clientKey},
+		ports.Field{Key: "duration", Val: ban.Remaining.String()},
+		ports.Field{Key: "strike", Val: ban.Strike})
+
+	info.Banned = true
+	info.RetryAfter = ban.Remaining
+	info.ResetTime = time.Now().Add(ban.Remaining).Unix()
+}
+
+// recordDecisions counts the decision of every limit of the request. When the
+// request is denied, only the limits that denied it are counted, as the others
+// did not consume anything. Without results per limit, every limit is counted
+// with the decision of the request.
+func (l *LimiterService) recordDecisions(route string, checks []ports.LimitCheck, infos []ports.RateLimitInfo, allowed bool) {
+	for i, check := range checks {
+		switch {
+		case allowed || len(infos) != len(checks):
+			l.metrics.RecordDecision(route, check.Algorithm, allowed)
+		case !infos[i].Allowed:
+			l.metrics.RecordDecision(route, check.Algorithm, false)
+		}
+	}
+}
+
+// evaluate runs a single limit directly and stacked limits atomically. A dry run
+// checks every limit through the multi limit script, which consumes nothing.
+func (l *LimiterService) evaluate(ctx context.Context, checks []ports.LimitCheck, dryRun bool) ([]ports.RateLimitInfo, error) {
+	if dryRun {
+		// Nothing was acquired, so there are no leases to release
+		infos, err := l.multiLimiter.CheckAll(ctx, checks)
+		for i := range infos {
+			infos[i].Lease = nil
+		}
+		return infos, err
+	}
+
+	if len(checks) == 1 {
+		check := checks[0]
+		info, err := l.limiters[check.Algorithm].Allow(ctx, check.Key, check.Cost, check.Config)
+		if err != nil {
+			return nil, err
+		}
+		return []ports.RateLimitInfo{info}, nil
+	}
+
+	return l.multiLimiter.AllowAll(ctx, checks)
+}
+
+func (l *LimiterService) releaseLease(lease ports.Lease, route string) {
+	if lease == nil {
+		return
+	}
+	if err := lease.Release(context.Background()); err != nil {
+		l.logger.Error("LimiterService: Allow: failed to release lease",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "error", Val: err})
+	}
+}
+
+func (l *LimiterService) buildRateLimitKey(limit config.LimitConfig, route, clientKey string, stacked bool) string {
+	if stacked {
+		return fmt.Sprintf(stackedRateLimitKeyPrefix, limit.Algorithm, route, clientKey, limit.Name)
+	}
+	return fmt.Sprintf(rateLimitKeyPrefix, limit.Algorithm, route, clientKey)
+}
+
+// policyName names a limit in the RateLimit headers: by its route, or by its
+// own name among stacked limits.
+func policyName(limit config.LimitConfig, route string, stacked bool) string {
+	if stacked {
+		return limit.Name
+	}
+	return route
+}
+
+// withPolicies names the results of checks after the limits they report.
+func withPolicies(checks []ports.LimitCheck, infos []ports.RateLimitInfo) []ports.RateLimitInfo {
+	for i := range infos {
+		if i < len(checks) {
+			infos[i].Policy = checks[i].Policy
+		}
+	}
+	return infos
+}
+
+func limitPolicies(checks []ports.LimitCheck) []ports.LimitPolicy {
+	policies := make([]ports.LimitPolicy, len(checks))
+	for i, check := range checks {
+		policies[i] = ports.LimitPolicy{Name: check.Policy, Quota: check.Config.Quota()}
+	}
+	return policies
+}
+
+// AggregateIP returns ip aggregated to the IP prefix of route, which is the
+// client key of requests from ip when the route keys by IP. Anything other than
+// an IP is returned unchanged.
+func (l *LimiterService) AggregateIP(route, ip string) string {
+	routeConfig, _, _ := l.configService.GetConfig().Lookup(route)
+	return routeConfig.IPPrefix.Apply(ip)
+}
+
+// KeyPatterns returns glob patterns matching the limiter keys of route, built
+// from the algorithms and names of its configured limits, for every client when
+// clientKey is empty. Windowed algorithms add the keys of their current and
+// previous windows. Routes without limits have no keys.
+func (l *LimiterService) KeyPatterns(route, clientKey string) []string {
+	cfg := l.configService.GetConfig()
+	routeConfig, ok := cfg.Routes[route]
+	if route == config.DefaultRoute && cfg.Default != nil {
+		routeConfig, ok = *cfg.Default, true
+	}
+	if !ok {
+		return nil
+	}
+
+	client := "*"
+	if clientKey != "" {
+		client = ports.EscapePattern(routeConfig.IPPrefix.Apply(clientKey))
+	}
+
+	stacked := len(routeConfig.Limits) > 1
+	patterns := make([]string, 0, len(routeConfig.Limits))
+	for _, limit := range routeConfig.Limits {
+		limit.Name = ports.EscapePattern(limit.Name)
+		key := l.buildRateLimitKey(limit, ports.EscapePattern(route), client, stacked)
+
+		scripted, ok := l.limiters[limit.Algorithm].(ports.ScriptedRateLimiter)
+		if !ok {
+			patterns = append(patterns, key)
+			continue
+		}
+		call, err := scripted.BuildCall(key, 1, limit.Config)
+		if err != nil {
+			patterns = append(patterns, key)
+			continue
+		}
+		patterns = append(patterns, call.Keys...)
+	}
+	return patterns
+}
+
+// mostRestrictive merges the results of stacked limits into the one reported to
+// the client: the rejecting limit with the latest reset, otherwise the limit with
+// the fewest remaining requests. Leases of every limit are kept and the longest
+// delay applies.
+func mostRestrictive(infos []ports.RateLimitInfo) ports.RateLimitInfo {
+	if len(infos) == 1 {
+		return infos[0]
+	}
+
+	var leases leaseGroup
+	var delay time.Duration
+	selected := infos[0]
+
+	for _, info := range infos {
+		if info.Lease != nil {
+			leases = append(leases, info.Lease)
+		}
+		if info.Delay > delay {
+			delay = info.Delay
+		}
+
+		switch {
+		case selected.Allowed && !info.Allowed:
+			selected = info
+		case selected.Allowed != info.Allowed:
+		case !info.Allowed && info.ResetTime > selected.ResetTime:
+			selected = info
+		case info.Allowed && info.Remaining < selected.Remaining:
+			selected = info
+		}
+	}
+
+	selected.Delay = delay
+	selected.Lease = nil
+	if selected.Allowed && len(leases) > 0 {
+		selected.Lease = leases
+	}
+	return selected
+}
+
+// leaseGroup releases the leases of every stacked limit.
+type leaseGroup []ports.Lease
+
+func (g leaseGroup) Release(ctx context.Context) error {
+	var firstErr error
+	for _, lease := range g {
+		if err := lease.Release(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// waitForDelay holds the request until its queue slot drains or ctx is done.
+func waitForDelay(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// AccessConfig lists the client IPs that bypass rate limiting and the ones that
+// are refused, globally or for a route. Deny entries take precedence.
+type AccessConfig struct {
+	// Allow lists the clients that are not rate limited.
+	Allow IPRanges
+	// Deny lists the clients whose requests are refused.
+	Deny IPRanges
+}
+
+// IPRanges is a list of IPv4 and IPv6 networks; single addresses are kept as
+// networks of their full length.
+type IPRanges []netip.Prefix
+
+// ParseIPRanges parses addresses ("192.0.2.1", "2001:db8::1") and CIDR ranges
+// ("192.0.2.0/24", "2001:db8::/32").
+func ParseIPRanges(entries []string) (IPRanges, error) {
+	ranges := make(IPRanges, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+					"invalid CIDR range",
+					fmt.Errorf("invalid CIDR range %q: %w", entry, err))
+			}
+			ranges = append(ranges, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"invalid IP address",
+				fmt.Errorf("invalid IP address %q: %w", entry, err))
+		}
+		addr = addr.Unmap().WithZone("")
+		ranges = append(ranges, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return ranges, nil
+}
+
+// Contains reports whether ip belongs to one of the ranges. IPv4-mapped IPv6
+// addresses match IPv4 ranges.
+func (r IPRanges) Contains(ip string) bool {
+	if len(r) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	return r.ContainsAddr(addr)
+}
+
+// ContainsAddr reports whether addr belongs to one of the ranges.
+func (r IPRanges) ContainsAddr(addr netip.Addr) bool {
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range r {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,22 +2,130 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
 )
 
 // Algorithm type constants
 const (
-	AlgorithmFixedWindow   = "fixed_window"
-	AlgorithmTokenBucket   = "token_bucket"
-	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmFixedWindow          = "fixed_window"
+	AlgorithmTokenBucket          = "token_bucket"
+	AlgorithmSlidingWindow        = "sliding_window"
+	AlgorithmLeakyBucket          = "leaky_bucket"
+	AlgorithmGCRA                 = "gcra"
+	AlgorithmSlidingWindowCounter = "sliding_window_counter"
+	AlgorithmConcurrency          = "concurrency"
 )
 
+// Unknown route policies
+const (
+	UnknownRouteAllow   = "allow"
+	UnknownRouteDefault = "default"
+	UnknownRouteReject  = "reject"
+)
+
+// RoutePolicyConfigured reports a request handled by its own route, as opposed
+// to one of the unknown route policies.
+const RoutePolicyConfigured = "route"
+
+// DefaultRoute is the route name the default route is keyed and logged under,
+// reserved so no configured route shares its keys.
+const DefaultRoute = "default"
+
 type Config struct {
 	Routes map[string]RouteConfig
+	// Default is the catch-all route applied to unknown routes, if any.
+	Default *RouteConfig
+	// UnknownRoute is the policy for routes missing from Routes, see UnknownRoutePolicy.
+	UnknownRoute string
+	// Failure is the global failure config, overridden per route.
+	Failure FailureConfig
+	// Access lists the clients allowed unlimited or denied on every route.
+	Access AccessConfig
 }
 
+// UnknownRoutePolicy returns the policy for unknown routes. When unset it applies
+// the default route if one is configured and allows requests unlimited otherwise.
+func (c Config) UnknownRoutePolicy() string {
+	if c.UnknownRoute != "" {
+		return c.UnknownRoute
+	}
+	if c.Default != nil {
+		return UnknownRouteDefault
+	}
+	return UnknownRouteAllow
+}
+
+// Lookup returns the configuration applied to route and the route policy that
+// selected it. Unknown routes resolve to the default route under the
+// UnknownRouteDefault policy and to no configuration otherwise.
+func (c Config) Lookup(route string) (RouteConfig, string, bool) {
+	if routeConfig, ok := c.Routes[route]; ok {
+		return routeConfig, RoutePolicyConfigured, true
+	}
+
+	policy := c.UnknownRoutePolicy()
+	if policy == UnknownRouteDefault && c.Default != nil {
+		return *c.Default, policy, true
+	}
+	return RouteConfig{}, policy, false
+}
+
+func (c Config) Validate() error {
+	if err := c.Failure.Validate(); err != nil {
+		return err
+	}
+
+	if _, ok := c.Routes[DefaultRoute]; ok {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"reserved route name",
+			fmt.Errorf("route name %q is reserved for the default route", DefaultRoute))
+	}
+
+	switch c.UnknownRoute {
+	case "", UnknownRouteAllow, UnknownRouteReject:
+	case UnknownRouteDefault:
+		if c.Default == nil {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"default route required",
+				fmt.Errorf("unknown_route %q requires a default route", c.UnknownRoute))
+		}
+	default:
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid unknown route policy",
+			fmt.Errorf("unknown_route must be %q, %q or %q, got %q",
+				UnknownRouteAllow, UnknownRouteDefault, UnknownRouteReject, c.UnknownRoute))
+	}
+	return nil
+}
+
+// RouteConfig holds the limits of a route. A request is allowed only when
+// every limit passes, e.g. a short burst limit plus a long sustained quota.
 type RouteConfig struct {
+	Limits []LimitConfig
+	Cost   CostConfig
+	Key    KeyConfig
+	// Match selects the requests of the route without the X-Rate-Limit-Rule header.
+	Match []RouteMatcher
+	// Failure overrides the global failure config for the route.
+	Failure FailureConfig
+	// Penalty bans clients of the route rejected too often.
+	Penalty PenaltyConfig
+	// Access lists the clients allowed unlimited or denied on the route, on
+	// top of the global lists.
+	Access AccessConfig
+	// IPPrefix aggregates client IPs before the penalty box and keys apply.
+	IPPrefix IPPrefixConfig
+	// Rejection is the response to requests rejected by the route.
+	Rejection RejectionConfig
+	// Upstream is the pool of backends allowed requests are proxied to.
+	Upstream UpstreamConfig
+}
+
+type LimitConfig struct {
+	// Name distinguishes the keys of stacked limits of the same route.
+	Name      string
 	Algorithm string
 	Config    AlgorithmConfig
 }
@@ -25,6 +133,8 @@ type RouteConfig struct {
 type AlgorithmConfig interface {
 	AlgorithmName() string
 	Validate() error
+	// Quota approximates the limit for the local fallback limiter.
+	Quota() Quota
 }
 
 type FixedWindowConfig struct {
@@ -36,6 +146,10 @@ func (f FixedWindowConfig) AlgorithmName() string {
 	return AlgorithmFixedWindow
 }
 
+func (f FixedWindowConfig) Quota() Quota {
+	return Quota{Limit: f.Limit, Period: time.Duration(f.Window) * time.Second}
+}
+
 func (f FixedWindowConfig) Validate() error {
 	if f.Limit <= 0 {
 		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
@@ -65,6 +179,10 @@ func (t TokenBucketConfig) AlgorithmName() string {
 	return AlgorithmTokenBucket
 }
 
+func (t TokenBucketConfig) Quota() Quota {
+	return Quota{Limit: t.Capacity, Period: refillPeriod(t.Capacity, t.RefillRate)}
+}
+
 func (t TokenBucketConfig) Validate() error {
 	if t.Capacity <= 0 {
 		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
@@ -98,6 +216,10 @@ func (s SlidingWindowConfig) AlgorithmName() string {
 	return AlgorithmSlidingWindow
 }
 
+func (s SlidingWindowConfig) Quota() Quota {
+	return Quota{Limit: s.Limit, Period: time.Duration(s.Window) * time.Second}
+}
+
 func (s SlidingWindowConfig) Validate() error {
 	if s.Limit <= 0 {
 		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
@@ -116,3 +238,145 @@ func (s SlidingWindowConfig) Validate() error {
 	}
 	return nil
 }
+
+type LeakyBucketConfig struct {
+	Capacity int
+	LeakRate int
+}
+
+func (l LeakyBucketConfig) AlgorithmName() string {
+	return AlgorithmLeakyBucket
+}
+
+func (l LeakyBucketConfig) Quota() Quota {
+	return Quota{Limit: l.Capacity, Period: refillPeriod(l.Capacity, l.LeakRate)}
+}
+
+func (l LeakyBucketConfig) Validate() error {
+	if l.Capacity <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"capacity must be positive",
+			fmt.Errorf("capacity must be positive, got %d", l.Capacity))
+	}
+	if l.LeakRate <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"leak_rate must be positive",
+			fmt.Errorf("leak_rate must be positive, got %d", l.LeakRate))
+	}
+	if l.Capacity/l.LeakRate > 86400 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"queue drain time too large",
+			fmt.Errorf("queue drain time too large: %d seconds (max 24 hours)", l.Capacity/l.LeakRate))
+	}
+	return nil
+}
+
+type GCRAConfig struct {
+	Limit  int
+	Window int
+	Burst  int
+}
+
+func (g GCRAConfig) AlgorithmName() string {
+	return AlgorithmGCRA
+}
+
+// EffectiveBurst returns the number of requests allowed at once, defaulting to Limit.
+func (g GCRAConfig) EffectiveBurst() int {
+	if g.Burst > 0 {
+		return g.Burst
+	}
+	return g.Limit
+}
+
+func (g GCRAConfig) Quota() Quota {
+	return Quota{Limit: g.Limit, Period: time.Duration(g.Window) * time.Second}
+}
+
+func (g GCRAConfig) Validate() error {
+	if g.Limit <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"limit must be positive",
+			fmt.Errorf("limit must be positive, got %d", g.Limit))
+	}
+	if g.Window <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"window must be positive",
+			fmt.Errorf("window must be positive, got %d", g.Window))
+	}
+	if g.Window > 86400 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"window too large",
+			fmt.Errorf("window too large: %d seconds (max 24 hours)", g.Window))
+	}
+	if g.Burst < 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"burst must not be negative",
+			fmt.Errorf("burst must not be negative, got %d", g.Burst))
+	}
+	return nil
+}
+
+type SlidingWindowCounterConfig struct {
+	Limit  int
+	Window int
+}
+
+func (s SlidingWindowCounterConfig) AlgorithmName() string {
+	return AlgorithmSlidingWindowCounter
+}
+
+func (s SlidingWindowCounterConfig) Quota() Quota {
+	return Quota{Limit: s.Limit, Period: time.Duration(s.Window) * time.Second}
+}
+
+func (s SlidingWindowCounterConfig) Validate() error {
+	if s.Limit <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"limit must be positive",
+			fmt.Errorf("limit must be positive, got %d", s.Limit))
+	}
+	if s.Window <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"window must be positive",
+			fmt.Errorf("window must be positive, got %d", s.Window))
+	}
+	if s.Window > 86400 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"window too large",
+			fmt.Errorf("window too large: %d seconds (max 24 hours)", s.Window))
+	}
+	return nil
+}
+
+type ConcurrencyConfig struct {
+	Limit    int
+	LeaseTTL int
+}
+
+func (c ConcurrencyConfig) AlgorithmName() string {
+	return AlgorithmConcurrency
+}
+
+func (c ConcurrencyConfig) Quota() Quota {
+	return Quota{Limit: c.Limit, InFlight: true}
+}
+
+func (c ConcurrencyConfig) Validate() error {
+	if c.Limit <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"limit must be positive",
+			fmt.Errorf("limit must be positive, got %d", c.Limit))
+	}
+	if c.LeaseTTL <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"lease_ttl must be positive",
+			fmt.Errorf("lease_ttl must be positive, got %d", c.LeaseTTL))
+	}
+	if c.LeaseTTL > 86400 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"lease_ttl too large",
+			fmt.Errorf("lease_ttl too large: %d seconds (max 24 hours)", c.LeaseTTL))
+	}
+	return nil
+}
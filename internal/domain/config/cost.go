@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// DefaultCost is the number of units a request consumes when its route defines no cost.
+const DefaultCost = 1
+
+// CostConfig describes how many units of the limit a request consumes.
+// Rules are evaluated in order of precedence: path, method, default. A header
+// cost may only raise the cost of the matched rule, as clients can send it.
+type CostConfig struct {
+	// Default is the static cost of the route.
+	Default int
+	// Header names a request header carrying the cost as a positive integer,
+	// which applies when above the rule cost.
+	Header string
+	// Methods maps HTTP methods to their cost.
+	Methods map[string]int
+	// Paths assigns costs to path prefixes; the longest matching prefix wins.
+	Paths []PathCost
+	// Max caps the resolved cost, mainly to bound header supplied costs.
+	Max int
+}
+
+type PathCost struct {
+	Prefix string
+	Cost   int
+}
+
+// Resolve returns the cost of a request, headerValue being the value of the Header request header.
+func (c CostConfig) Resolve(method, path, headerValue string) int {
+	return c.clamp(c.resolve(method, path, headerValue))
+}
+
+func (c CostConfig) resolve(method, path, headerValue string) int {
+	cost := c.ruleCost(method, path)
+	if c.Header != "" && headerValue != "" {
+		if headerCost, err := strconv.Atoi(strings.TrimSpace(headerValue)); err == nil && headerCost > cost {
+			return headerCost
+		}
+	}
+	return cost
+}
+
+// ruleCost returns the cost of the longest matching path prefix, the method or
+// the default, in that order.
+func (c CostConfig) ruleCost(method, path string) int {
+	matched := -1
+	for i, p := range c.Paths {
+		if strings.HasPrefix(path, p.Prefix) && (matched == -1 || len(p.Prefix) > len(c.Paths[matched].Prefix)) {
+			matched = i
+		}
+	}
+	if matched != -1 {
+		return c.Paths[matched].Cost
+	}
+
+	if cost, ok := c.Methods[strings.ToUpper(method)]; ok {
+		return cost
+	}
+
+	if c.Default > 0 {
+		return c.Default
+	}
+	return DefaultCost
+}
+
+// Clamp bounds a cost given by the caller to the route, from DefaultCost to Max.
+func (c CostConfig) Clamp(cost int) int {
+	if cost < DefaultCost {
+		return DefaultCost
+	}
+	return c.clamp(cost)
+}
+
+func (c CostConfig) clamp(cost int) int {
+	if c.Max > 0 && cost > c.Max {
+		return c.Max
+	}
+	return cost
+}
+
+func (c CostConfig) Validate() error {
+	if c.Default < 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"cost must not be negative",
+			fmt.Errorf("cost must not be negative, got %d", c.Default))
+	}
+	if c.Max < 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"max cost must not be negative",
+			fmt.Errorf("max cost must not be negative, got %d", c.Max))
+	}
+	for method, cost := range c.Methods {
+		if cost <= 0 {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"method cost must be positive",
+				fmt.Errorf("cost for method %s must be positive, got %d", method, cost))
+		}
+	}
+	for _, p := range c.Paths {
+		if p.Prefix == "" {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"path cost prefix must not be empty",
+				fmt.Errorf("path cost prefix must not be empty"))
+		}
+		if p.Cost <= 0 {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"path cost must be positive",
+				fmt.Errorf("cost for path %s must be positive, got %d", p.Prefix, p.Cost))
+		}
+	}
+	return nil
+}
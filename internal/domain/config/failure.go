@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// Failure mode constants
+const (
+	FailureModeOpen   = "open"
+	FailureModeClosed = "closed"
+	FailureModeLocal  = "local"
+)
+
+// Failure mode defaults
+const (
+	DefaultFailureMode       = FailureModeOpen
+	DefaultLocalFraction     = 1.0
+	DefaultFailureRetryAfter = 5
+)
+
+// FailureConfig decides how requests are handled while the limiter store is
+// unavailable. A zero FailureConfig inherits the global one.
+type FailureConfig struct {
+	// Mode is FailureModeOpen, FailureModeClosed or FailureModeLocal.
+	Mode string
+	// LocalFraction is the share of each limit enforced by the local limiter of
+	// every instance, typically 1 divided by the number of instances.
+	LocalFraction float64
+	// RetryAfter is the number of seconds clients are asked to wait when failing closed.
+	RetryAfter int
+}
+
+// FailureFor returns the failure config of a route, falling back to the global
+// config and then to the defaults.
+func (c Config) FailureFor(route RouteConfig) FailureConfig {
+	failure := route.Failure
+	if failure.Mode == "" {
+		failure = c.Failure
+	}
+	if failure.Mode == "" {
+		failure.Mode = DefaultFailureMode
+	}
+	if failure.LocalFraction <= 0 {
+		failure.LocalFraction = DefaultLocalFraction
+	}
+	if failure.RetryAfter <= 0 {
+		failure.RetryAfter = DefaultFailureRetryAfter
+	}
+	return failure
+}
+
+func (f FailureConfig) Validate() error {
+	switch f.Mode {
+	case "", FailureModeOpen, FailureModeClosed, FailureModeLocal:
+	default:
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid failure mode",
+			fmt.Errorf("failure mode must be %q, %q or %q, got %q",
+				FailureModeOpen, FailureModeClosed, FailureModeLocal, f.Mode))
+	}
+	if f.LocalFraction < 0 || f.LocalFraction > 1 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"local fraction must be between 0 and 1",
+			fmt.Errorf("local fraction must be between 0 and 1, got %g", f.LocalFraction))
+	}
+	if f.RetryAfter < 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"retry after must not be negative",
+			fmt.Errorf("retry after must not be negative, got %d", f.RetryAfter))
+	}
+	return nil
+}
+
+// Quota is the approximation of a limit enforced by the local fallback limiter:
+// Limit units refilled every Period, or Limit requests in flight.
+type Quota struct {
+	Limit    int
+	Period   time.Duration
+	InFlight bool
+}
+
+// Scale returns the share of the quota enforced by a single instance, never below one unit.
+func (q Quota) Scale(fraction float64) Quota {
+	q.Limit = int(math.Ceil(float64(q.Limit) * fraction))
+	if q.Limit < 1 {
+		q.Limit = 1
+	}
+	return q
+}
+
+func refillPeriod(capacity, rate int) time.Duration {
+	if rate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(capacity) / float64(rate) * float64(time.Second))
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"net/netip"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// IPPrefixConfig aggregates the clients of a route to networks, so that every
+// address of an IPv6 allocation shares one budget rather than each getting its
+// own. IPv4 and IPv6 are the prefix lengths clients are aggregated to, such as
+// 24 and 64; 0 keeps addresses of the family apart.
+type IPPrefixConfig struct {
+	IPv4 int
+	IPv6 int
+}
+
+func (p IPPrefixConfig) Enabled() bool {
+	return p.IPv4 > 0 || p.IPv6 > 0
+}
+
+// Apply returns the network address of the prefix ip belongs to, or ip itself
+// when the family isn't aggregated or ip isn't an address.
+func (p IPPrefixConfig) Apply(ip string) string {
+	if !p.Enabled() {
+		return ip
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap().WithZone("")
+
+	bits := p.IPv6
+	if addr.Is4() {
+		bits = p.IPv4
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return ip
+	}
+
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ip
+	}
+	return prefix.Addr().String()
+}
+
+func (p IPPrefixConfig) Validate() error {
+	if p.IPv4 < 0 || p.IPv4 > 32 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"IPv4 prefix length must be between 0 and 32",
+			fmt.Errorf("IPv4 prefix length must be between 0 and 32, got %d", p.IPv4))
+	}
+	if p.IPv6 < 0 || p.IPv6 > 128 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"IPv6 prefix length must be between 0 and 128",
+			fmt.Errorf("IPv6 prefix length must be between 0 and 128, got %d", p.IPv6))
+	}
+	return nil
+}
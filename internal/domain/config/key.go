@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// Key source constants
+const (
+	KeySourceLiteral = ""
+	KeySourceIP      = "ip"
+	KeySourceHeader  = "header"
+	KeySourceQuery   = "query"
+	KeySourceCookie  = "cookie"
+	KeySourceJWT     = "jwt"
+)
+
+// Behaviour when a key source is missing from the request
+const (
+	KeyMissingIP     = "ip"
+	KeyMissingReject = "reject"
+)
+
+// CallerKeyPrefix prefixes the client keys given by callers of the check API
+// and by embedding applications, so that no caller can take the limits of an IP
+// or of a key extracted from requests.
+const CallerKeyPrefix = "key:"
+
+// DescriptorKeyPrefix prefixes the client keys made of the descriptor entries of
+// the Envoy ratelimit service, keeping them apart from IPs, extracted keys and
+// the keys of other callers.
+const DescriptorKeyPrefix = "descriptor:"
+
+// JWT signing algorithms supported for verified claims
+var supportedJWTAlgorithms = map[string]struct{}{
+	"HS256": {}, "HS384": {}, "HS512": {},
+	"RS256": {}, "RS384": {}, "RS512": {},
+}
+
+// KeyConfig describes how the client key of a route is extracted from a request.
+// An empty KeyConfig keys clients by IP.
+type KeyConfig struct {
+	Segments []KeySegment
+	JWT      JWTConfig
+	// Missing is either KeyMissingIP (default) or KeyMissingReject.
+	Missing string
+}
+
+// KeySegment is either literal text or a value taken from the request.
+type KeySegment struct {
+	Source string
+	// Name is the header, query parameter, cookie or claim name, or the literal text.
+	Name string
+}
+
+// JWTConfig locates the token for jwt key sources and, when a secret or public
+// key is set, verifies its signature before trusting its claims.
+type JWTConfig struct {
+	Header    string
+	Cookie    string
+	Algorithm string
+	Secret    string
+	PublicKey string
+}
+
+func (j JWTConfig) Verified() bool {
+	return j.Secret != "" || j.PublicKey != ""
+}
+
+func (k KeyConfig) IsClientIP() bool {
+	return len(k.Segments) == 0
+}
+
+// ParseKeyTemplate parses a key template such as "{header:X-Tenant}:{jwt:sub}".
+// A template without placeholders is a single source, e.g. "header:X-API-Key".
+func ParseKeyTemplate(template string) ([]KeySegment, error) {
+	template = strings.TrimSpace(template)
+	if template == "" || template == KeySourceIP {
+		return nil, nil
+	}
+	if !strings.Contains(template, "{") {
+		segment, err := parseKeySource(template)
+		if err != nil {
+			return nil, err
+		}
+		return []KeySegment{segment}, nil
+	}
+
+	var segments []KeySegment
+	for len(template) > 0 {
+		start := strings.Index(template, "{")
+		if start == -1 {
+			segments = append(segments, KeySegment{Source: KeySourceLiteral, Name: template})
+			break
+		}
+		if start > 0 {
+			segments = append(segments, KeySegment{Source: KeySourceLiteral, Name: template[:start]})
+		}
+
+		end := strings.Index(template[start:], "}")
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated placeholder in key template %q", template)
+		}
+
+		segment, err := parseKeySource(template[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+		template = template[start+end+1:]
+	}
+	return segments, nil
+}
+
+func parseKeySource(source string) (KeySegment, error) {
+	kind, name, _ := strings.Cut(strings.TrimSpace(source), ":")
+	switch kind {
+	case KeySourceIP:
+		return KeySegment{Source: KeySourceIP}, nil
+	case KeySourceHeader, KeySourceQuery, KeySourceCookie, KeySourceJWT:
+		if name == "" {
+			return KeySegment{}, fmt.Errorf("key source %q requires a name", kind)
+		}
+		return KeySegment{Source: kind, Name: name}, nil
+	default:
+		return KeySegment{}, fmt.Errorf("unknown key source %q", kind)
+	}
+}
+
+func (k KeyConfig) Validate() error {
+	if k.Missing != "" && k.Missing != KeyMissingIP && k.Missing != KeyMissingReject {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid missing key behaviour",
+			fmt.Errorf("missing must be %q or %q, got %q", KeyMissingIP, KeyMissingReject, k.Missing))
+	}
+
+	// An algorithm without its secret or public key, e.g. from an unset
+	// variable, must not silently fall back to trusting unverified claims
+	if !k.JWT.Verified() && k.JWT.Algorithm == "" {
+		return nil
+	}
+	if _, ok := supportedJWTAlgorithms[k.JWT.Algorithm]; !ok {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"unsupported jwt algorithm",
+			fmt.Errorf("unsupported jwt algorithm %q", k.JWT.Algorithm))
+	}
+	if strings.HasPrefix(k.JWT.Algorithm, "HS") && k.JWT.Secret == "" {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"jwt secret required",
+			fmt.Errorf("jwt algorithm %s requires a secret", k.JWT.Algorithm))
+	}
+	if strings.HasPrefix(k.JWT.Algorithm, "RS") && k.JWT.PublicKey == "" {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"jwt public key required",
+			fmt.Errorf("jwt algorithm %s requires a public key", k.JWT.Algorithm))
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// Path match kinds, in order of increasing precedence
+const (
+	matchAnyPath = iota
+	matchRegex
+	matchGlob
+	matchPrefix
+	matchExact
+)
+
+// RouteMatcher selects the requests a route applies to. Empty fields match
+// anything, but at least one must be set; at most one of Path, Prefix, Glob
+// and Regex may be set.
+type RouteMatcher struct {
+	// Path matches the request path exactly.
+	Path string
+	// Prefix matches request paths starting with it.
+	Prefix string
+	// Glob matches the request path with path.Match syntax, "*" matching a single segment.
+	Glob string
+	// Regex matches the request path with a regular expression.
+	Regex *regexp.Regexp
+	// Methods restricts the matcher to the given HTTP methods.
+	Methods []string
+	// Host matches the request host, either exactly or as "*.example.com".
+	Host string
+}
+
+func (m RouteMatcher) kind() int {
+	switch {
+	case m.Path != "":
+		return matchExact
+	case m.Prefix != "":
+		return matchPrefix
+	case m.Glob != "":
+		return matchGlob
+	case m.Regex != nil:
+		return matchRegex
+	default:
+		return matchAnyPath
+	}
+}
+
+func (m RouteMatcher) pattern() string {
+	switch m.kind() {
+	case matchExact:
+		return m.Path
+	case matchPrefix:
+		return m.Prefix
+	case matchGlob:
+		return m.Glob
+	case matchRegex:
+		return m.Regex.String()
+	default:
+		return ""
+	}
+}
+
+// Matches reports whether the request matches every condition of m.
+func (m RouteMatcher) Matches(method, host, requestPath string) bool {
+	if len(m.Methods) > 0 && !containsFold(m.Methods, method) {
+		return false
+	}
+	if m.Host != "" && !matchHost(m.Host, host) {
+		return false
+	}
+
+	switch m.kind() {
+	case matchExact:
+		return requestPath == m.Path
+	case matchPrefix:
+		return strings.HasPrefix(requestPath, m.Prefix)
+	case matchGlob:
+		ok, _ := path.Match(m.Glob, requestPath)
+		return ok
+	case matchRegex:
+		return m.Regex.MatchString(requestPath)
+	default:
+		return true
+	}
+}
+
+// outranks reports whether m takes precedence over other: exact paths first,
+// then prefixes, globs and regexes, longer patterns before shorter ones, and
+// matchers constrained by method or host before unconstrained ones.
+func (m RouteMatcher) outranks(other RouteMatcher) (bool, bool) {
+	if m.kind() != other.kind() {
+		return m.kind() > other.kind(), true
+	}
+	if len(m.pattern()) != len(other.pattern()) {
+		return len(m.pattern()) > len(other.pattern()), true
+	}
+	if (len(m.Methods) > 0) != (len(other.Methods) > 0) {
+		return len(m.Methods) > 0, true
+	}
+	if (m.Host != "") != (other.Host != "") {
+		return m.Host != "", true
+	}
+	return false, false
+}
+
+func (m RouteMatcher) Validate() error {
+	set := 0
+	for _, field := range []bool{m.Path != "", m.Prefix != "", m.Glob != "", m.Regex != nil} {
+		if field {
+			set++
+		}
+	}
+	if set == 0 && len(m.Methods) == 0 && m.Host == "" {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"matcher must declare a condition",
+			fmt.Errorf("matcher declares none of path, prefix, glob, regex, methods and host"))
+	}
+	if set > 1 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"matcher must declare a single path condition",
+			fmt.Errorf("matcher declares %d of path, prefix, glob and regex", set))
+	}
+	if m.Glob != "" {
+		if _, err := path.Match(m.Glob, ""); err != nil {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"invalid glob pattern",
+				fmt.Errorf("invalid glob %q: %w", m.Glob, err))
+		}
+	}
+	return nil
+}
+
+// MatchRoute returns the route whose matchers best match the request. Ties
+// between equally specific matchers go to the route name that sorts first.
+func (c Config) MatchRoute(method, host, requestPath string) (string, bool) {
+	var (
+		bestRoute   string
+		bestMatcher RouteMatcher
+		found       bool
+	)
+
+	for route, routeConfig := range c.Routes {
+		for _, matcher := range routeConfig.Match {
+			if !matcher.Matches(method, host, requestPath) {
+				continue
+			}
+			if !found {
+				bestRoute, bestMatcher, found = route, matcher, true
+				continue
+			}
+			if better, decided := matcher.outranks(bestMatcher); better || (!decided && route < bestRoute) {
+				bestRoute, bestMatcher = route, matcher
+			}
+		}
+	}
+	return bestRoute, found
+}
+
+func matchHost(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return len(host) > len(suffix)+1 && strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(suffix))
+	}
+	return strings.EqualFold(pattern, host)
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
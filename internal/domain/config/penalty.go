@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// DefaultPenaltyMultiplier keeps every ban as long as the first one.
+const DefaultPenaltyMultiplier = 1.0
+
+// PenaltyConfig bans clients of a route once their requests have been rejected
+// Threshold times within Window seconds. Banned clients are rejected without
+// running the route limits. A zero PenaltyConfig disables the penalty box.
+type PenaltyConfig struct {
+	// Threshold is the number of rejections that bans the client.
+	Threshold int
+	// Window is the number of seconds rejections are counted over.
+	Window int
+	// Duration is the number of seconds of the first ban.
+	Duration int
+	// Multiplier grows every following ban of the client, e.g. 2 doubles them.
+	Multiplier float64
+	// MaxDuration caps growing bans in seconds, 0 for no cap.
+	MaxDuration int
+}
+
+func (p PenaltyConfig) Enabled() bool {
+	return p.Threshold > 0
+}
+
+// GrowthFactor returns Multiplier, defaulting to DefaultPenaltyMultiplier.
+func (p PenaltyConfig) GrowthFactor() float64 {
+	if p.Multiplier <= 0 {
+		return DefaultPenaltyMultiplier
+	}
+	return p.Multiplier
+}
+
+func (p PenaltyConfig) Validate() error {
+	if !p.Enabled() {
+		return nil
+	}
+	if p.Window <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"penalty window must be greater than 0",
+			fmt.Errorf("penalty window must be greater than 0, got %d", p.Window))
+	}
+	if p.Duration <= 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"penalty duration must be greater than 0",
+			fmt.Errorf("penalty duration must be greater than 0, got %d", p.Duration))
+	}
+	if p.Multiplier != 0 && p.Multiplier < 1 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"penalty multiplier must be at least 1",
+			fmt.Errorf("penalty multiplier must be at least 1, got %g", p.Multiplier))
+	}
+	if p.MaxDuration < 0 || (p.MaxDuration > 0 && p.MaxDuration < p.Duration) {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"penalty max duration must not be below the duration",
+			fmt.Errorf("penalty max duration must be 0 or at least %d, got %d", p.Duration, p.MaxDuration))
+	}
+	return nil
+}
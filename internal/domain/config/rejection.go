@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// Placeholders of rejection body templates and redirects
+const (
+	PlaceholderLimit      = "limit"
+	PlaceholderRemaining  = "remaining"
+	PlaceholderReset      = "reset"
+	PlaceholderRetryAfter = "retry_after"
+	PlaceholderRoute      = "route"
+	PlaceholderRequestID  = "request_id"
+	PlaceholderReason     = "reason"
+)
+
+// DefaultRejectionContentType is the content type of bodies declared without one.
+const DefaultRejectionContentType = "text/plain; charset=utf-8"
+
+var placeholderPattern = regexp.MustCompile(`\{\{([A-Za-z_][A-Za-z0-9_]*)\}\}`)
+
+// RejectionConfig is the response of a route to requests rejected by its limits
+// or its penalty box. Templates hold {{placeholder}} references, e.g. {{limit}}.
+// A zero RejectionConfig keeps the plain text 429 response.
+type RejectionConfig struct {
+	// Status is the status code, http.StatusTooManyRequests by default, or
+	// http.StatusSeeOther with a Redirect.
+	Status int
+	// Headers are added to the response.
+	Headers map[string]string
+	// Redirect is the template of a URL clients are redirected to.
+	Redirect string
+	// Bodies are the bodies clients negotiate with their Accept header; the
+	// first one is sent when none is acceptable.
+	Bodies []RejectionBody
+}
+
+type RejectionBody struct {
+	ContentType string
+	Template    string
+}
+
+func (r RejectionConfig) Enabled() bool {
+	return r.Status != 0 || len(r.Headers) > 0 || r.Redirect != "" || len(r.Bodies) > 0
+}
+
+// StatusCode returns Status or its default.
+func (r RejectionConfig) StatusCode() int {
+	switch {
+	case r.Status != 0:
+		return r.Status
+	case r.Redirect != "":
+		return http.StatusSeeOther
+	default:
+		return http.StatusTooManyRequests
+	}
+}
+
+func (r RejectionConfig) Validate() error {
+	status := r.StatusCode()
+	if status < 300 || status > 599 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid rejection status",
+			fmt.Errorf("rejection status must be between 300 and 599, got %d", status))
+	}
+	if r.Redirect != "" && (status < 300 || status > 399) {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid rejection status",
+			fmt.Errorf("rejection redirect requires a 3xx status, got %d", status))
+	}
+
+	for name := range r.Headers {
+		if strings.TrimSpace(name) == "" {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"invalid rejection header",
+				fmt.Errorf("rejection header name must not be empty"))
+		}
+	}
+
+	templates := []string{r.Redirect}
+	for _, body := range r.Bodies {
+		templates = append(templates, body.Template)
+	}
+	for _, template := range templates {
+		for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+			if !knownPlaceholder(match[1]) {
+				return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+					"unknown rejection placeholder",
+					fmt.Errorf("unknown rejection placeholder %q", match[0]))
+			}
+		}
+	}
+	return nil
+}
+
+func knownPlaceholder(name string) bool {
+	switch name {
+	case PlaceholderLimit, PlaceholderRemaining, PlaceholderReset, PlaceholderRetryAfter,
+		PlaceholderRoute, PlaceholderRequestID, PlaceholderReason:
+		return true
+	default:
+		return false
+	}
+}
+
+// RenderTemplate replaces the placeholders of template by their value, escaped
+// with escape.
+func RenderTemplate(template string, values map[string]string, escape func(string) string) string {
+	return placeholderPattern.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[2 : len(match)-2]
+		value, ok := values[name]
+		if !ok {
+			return match
+		}
+		return escape(value)
+	})
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+)
+
+// Upstream load balancers
+const (
+	BalancerRoundRobin       = "round_robin"
+	BalancerLeastConnections = "least_connections"
+)
+
+// Health check defaults
+const (
+	DefaultHealthCheckInterval  = 10
+	DefaultHealthCheckTimeout   = 2
+	DefaultHealthCheckThreshold = 1
+)
+
+// UpstreamConfig is the pool of backends allowed requests of a route are
+// proxied to. A zero UpstreamConfig proxies to the default backend.
+type UpstreamConfig struct {
+	// Targets are the base URLs of the backends, e.g. http://users:8080.
+	Targets []string
+	// Balancer spreads requests over the targets, BalancerRoundRobin by default.
+	Balancer string
+	// HealthCheck takes unhealthy targets out of the pool.
+	HealthCheck HealthCheckConfig
+	// ID is the Fingerprint of the config, set when it is parsed so that pools
+	// are compared once per reload rather than on every request.
+	ID string
+}
+
+// HealthCheckConfig probes every target with a GET of Path each Interval
+// seconds, a target being healthy while it answers 2xx or 3xx within Timeout
+// seconds. Targets change state after HealthyThreshold or UnhealthyThreshold
+// consecutive probes. A zero HealthCheckConfig keeps every target healthy.
+type HealthCheckConfig struct {
+	Path               string
+	Interval           int
+	Timeout            int
+	HealthyThreshold   int
+	UnhealthyThreshold int
+}
+
+func (u UpstreamConfig) Enabled() bool {
+	return len(u.Targets) > 0
+}
+
+// BalancerName returns Balancer, defaulting to BalancerRoundRobin.
+func (u UpstreamConfig) BalancerName() string {
+	if u.Balancer == "" {
+		return BalancerRoundRobin
+	}
+	return u.Balancer
+}
+
+// Fingerprint identifies the pool u describes: configs with the same targets,
+// balancer and health check have the same fingerprint.
+func (u UpstreamConfig) Fingerprint() string {
+	h := fnv.New64a()
+	_, _ = fmt.Fprintf(h, "%q %q %+v", u.Targets, u.Balancer, u.HealthCheck)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+func (u UpstreamConfig) Validate() error {
+	if !u.Enabled() {
+		return nil
+	}
+
+	for _, target := range u.Targets {
+		parsed, err := url.Parse(target)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+				"invalid upstream target",
+				fmt.Errorf("upstream target must be an http or https URL, got %q", target))
+		}
+	}
+
+	switch u.BalancerName() {
+	case BalancerRoundRobin, BalancerLeastConnections:
+	default:
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid upstream balancer",
+			fmt.Errorf("upstream balancer must be %q or %q, got %q", BalancerRoundRobin, BalancerLeastConnections, u.Balancer))
+	}
+
+	return u.HealthCheck.Validate()
+}
+
+func (h HealthCheckConfig) Enabled() bool {
+	return h.Path != ""
+}
+
+// IntervalDuration returns Interval, defaulting to DefaultHealthCheckInterval.
+func (h HealthCheckConfig) IntervalDuration() time.Duration {
+	return secondsOrDefault(h.Interval, DefaultHealthCheckInterval)
+}
+
+// TimeoutDuration returns Timeout, defaulting to DefaultHealthCheckTimeout or
+// the interval when shorter.
+func (h HealthCheckConfig) TimeoutDuration() time.Duration {
+	return min(secondsOrDefault(h.Timeout, DefaultHealthCheckTimeout), h.IntervalDuration())
+}
+
+// Thresholds return HealthyThreshold and UnhealthyThreshold, defaulting to
+// DefaultHealthCheckThreshold.
+func (h HealthCheckConfig) Thresholds() (healthy, unhealthy int) {
+	healthy, unhealthy = h.HealthyThreshold, h.UnhealthyThreshold
+	if healthy <= 0 {
+		healthy = DefaultHealthCheckThreshold
+	}
+	if unhealthy <= 0 {
+		unhealthy = DefaultHealthCheckThreshold
+	}
+	return healthy, unhealthy
+}
+
+func (h HealthCheckConfig) Validate() error {
+	if !h.Enabled() {
+		return nil
+	}
+	if h.Path[0] != '/' {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid health check path",
+			fmt.Errorf("health check path must start with /, got %q", h.Path))
+	}
+	if h.Interval < 0 || h.Timeout < 0 || h.HealthyThreshold < 0 || h.UnhealthyThreshold < 0 {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid health check",
+			fmt.Errorf("health check interval, timeout and thresholds must not be negative"))
+	}
+	if h.Timeout > 0 && time.Duration(h.Timeout)*time.Second > h.IntervalDuration() {
+		return errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid health check timeout",
+			fmt.Errorf("health check timeout must not exceed the interval, got %ds > %s", h.Timeout, h.IntervalDuration()))
+	}
+	return nil
+}
+
+func secondsOrDefault(seconds, defaultSeconds int) time.Duration {
+	if seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -1,6 +1,9 @@
 package errors
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type RateLimiterError struct {
 	Code    string
@@ -19,6 +22,13 @@ func (e *RateLimiterError) Unwrap() error {
 	return e.Err
 }
 
+// Is matches the RateLimiterErrors of the same code, so errors created with
+// NewRateLimiterError match the sentinel errors of their code.
+func (e *RateLimiterError) Is(target error) bool {
+	t, ok := target.(*RateLimiterError)
+	return ok && t.Code == e.Code
+}
+
 func NewRateLimiterError(code, message string, err error) *RateLimiterError {
 	return &RateLimiterError{
 		Code:    code,
@@ -48,4 +58,16 @@ var (
 		Code:    "CONSUL_ERROR",
 		Message: "consul operation failed",
 	}
+	// ErrStoreUnavailable reports a limiter store that couldn't be reached, as
+	// opposed to one that answered with an error.
+	ErrStoreUnavailable = &RateLimiterError{
+		Code:    "STORE_UNAVAILABLE",
+		Message: "limiter store unavailable",
+	}
 )
+
+// IsStoreUnavailable reports whether err is due to the limiter store being
+// unreachable, e.g. a Redis timeout or an open circuit breaker.
+func IsStoreUnavailable(err error) bool {
+	return errors.Is(err, ErrStoreUnavailable)
+}
@@ -2,46 +2,51 @@ package limiter
 
 import (
 	"fmt"
-	"net"
-	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// Access is the decision of a Policy for a client IP.
+type Access int
+
+const (
+	// AccessLimit applies the rate limits of the route.
+	AccessLimit Access = iota
+	// AccessBypass lets the request through without rate limiting.
+	AccessBypass
+	// AccessDeny refuses the request.
+	AccessDeny
 )
 
+// Policy decides which clients bypass rate limiting or are refused, from the
+// whitelist of the bootstrap config and the access lists of the rate limiting
+// config, which hot-reload with it.
 type Policy struct {
-	whitelistedIPs map[string]struct{}
+	whitelist config.IPRanges
 }
 
+// NewPolicy creates a policy whitelisting the given addresses and CIDR ranges.
 func NewPolicy(whitelistedIPs []string) (*Policy, error) {
-	if len(whitelistedIPs) == 0 {
-		return &Policy{whitelistedIPs: make(map[string]struct{})}, nil
-	}
-
-	whitelist := make(map[string]struct{}, len(whitelistedIPs))
-	for _, ip := range whitelistedIPs {
-		normalized := strings.TrimSpace(ip)
-		if normalized == "" {
-			continue
-		}
-		if err := validateIP(normalized); err != nil {
-			return nil, fmt.Errorf("invalid whitelisted IP '%s': %w", ip, err)
-		}
-		whitelist[normalized] = struct{}{}
+	whitelist, err := config.ParseIPRanges(whitelistedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid whitelisted IPs: %w", err)
 	}
-
-	return &Policy{whitelistedIPs: whitelist}, nil
+	return &Policy{whitelist: whitelist}, nil
 }
 
-func (p *Policy) ShouldBypassRateLimit(ip string) bool {
-	_, exists := p.whitelistedIPs[ip]
-	return exists
+// Decide returns the access decision for ip given the global and route access
+// lists. Deny lists take precedence over every allow list.
+func (p *Policy) Decide(ip string, global, route config.AccessConfig) Access {
+	switch {
+	case route.Deny.Contains(ip) || global.Deny.Contains(ip):
+		return AccessDeny
+	case p.whitelist.Contains(ip) || global.Allow.Contains(ip) || route.Allow.Contains(ip):
+		return AccessBypass
+	default:
+		return AccessLimit
+	}
 }
 
 func (p *Policy) WhitelistedIPsCount() int {
-	return len(p.whitelistedIPs)
-}
-
-func validateIP(ip string) error {
-	if net.ParseIP(ip) == nil {
-		return fmt.Errorf("invalid IP address format")
-	}
-	return nil
+	return len(p.whitelist)
 }
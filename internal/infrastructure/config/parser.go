@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
 	domainConfig "github.com/SilentPlaces/rate_limiter/internal/domain/config"
@@ -20,19 +24,239 @@ func (p *Parser) Parse(data []byte) (domainConfig.Config, error) {
 		return domainConfig.Config{}, fmt.Errorf("failed to unmarshal limiter config: %w", err)
 	}
 
-	return dtoToDomain(dto), nil
+	return dtoToDomain(dto)
 }
 
 type limiterConfigDTO struct {
-	Routes map[string]routeConfigDTO `json:"routes"`
+	Routes       map[string]routeConfigDTO `json:"routes"`
+	Default      *routeConfigDTO           `json:"default"`
+	UnknownRoute string                    `json:"unknown_route"`
+	Failure      failureConfigDTO          `json:"failure"`
+	Access       accessConfigDTO           `json:"access"`
 }
 
+// routeConfigDTO accepts either a single limit declared inline with the route
+// or a list of stacked limits under "limits".
 type routeConfigDTO struct {
+	Limits    []limitConfigDTO   `json:"limits"`
+	Cost      costConfigDTO      `json:"cost"`
+	Key       keyConfigDTO       `json:"key"`
+	Match     matchersDTO        `json:"match"`
+	Failure   failureConfigDTO   `json:"failure"`
+	Penalty   penaltyConfigDTO   `json:"penalty"`
+	Access    accessConfigDTO    `json:"access"`
+	IPPrefix  ipPrefixConfigDTO  `json:"ip_prefix"`
+	Rejection rejectionConfigDTO `json:"rejection"`
+	Upstream  upstreamConfigDTO  `json:"upstream"`
+}
+
+// upstreamConfigDTO accepts either a URL ("upstream": "http://users:8080"), a
+// list of URLs or a pool object.
+type upstreamConfigDTO struct {
+	Targets     []string             `json:"targets"`
+	Balancer    string               `json:"balancer"`
+	HealthCheck healthCheckConfigDTO `json:"health_check"`
+}
+
+type healthCheckConfigDTO struct {
+	Path               string `json:"path"`
+	Interval           int    `json:"interval"`
+	Timeout            int    `json:"timeout"`
+	HealthyThreshold   int    `json:"healthy_threshold"`
+	UnhealthyThreshold int    `json:"unhealthy_threshold"`
+}
+
+func (u *upstreamConfigDTO) UnmarshalJSON(data []byte) error {
+	var target string
+	if err := json.Unmarshal(data, &target); err == nil {
+		u.Targets = []string{target}
+		return nil
+	}
+
+	var targets []string
+	if err := json.Unmarshal(data, &targets); err == nil {
+		u.Targets = targets
+		return nil
+	}
+
+	type alias upstreamConfigDTO
+	var pool alias
+	if err := json.Unmarshal(data, &pool); err != nil {
+		return fmt.Errorf("upstream must be a URL, a list of URLs or an object: %w", err)
+	}
+	*u = upstreamConfigDTO(pool)
+	return nil
+}
+
+// rejectionConfigDTO accepts a single body with its content type, or a list of
+// bodies to negotiate.
+type rejectionConfigDTO struct {
+	Status      int                `json:"status"`
+	Headers     map[string]string  `json:"headers"`
+	Redirect    string             `json:"redirect"`
+	ContentType string             `json:"content_type"`
+	Body        string             `json:"body"`
+	Bodies      []rejectionBodyDTO `json:"bodies"`
+}
+
+type rejectionBodyDTO struct {
+	ContentType string `json:"content_type"`
+	Body        string `json:"body"`
+}
+
+type ipPrefixConfigDTO struct {
+	IPv4 int `json:"ipv4"`
+	IPv6 int `json:"ipv6"`
+}
+
+// accessConfigDTO lists IP addresses and CIDR ranges.
+type accessConfigDTO struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+type penaltyConfigDTO struct {
+	Threshold   int     `json:"threshold"`
+	Window      int     `json:"window"`
+	Duration    int     `json:"duration"`
+	Multiplier  float64 `json:"multiplier"`
+	MaxDuration int     `json:"max_duration"`
+}
+
+type limitConfigDTO struct {
+	Name      string          `json:"name"`
 	Algorithm string          `json:"algorithm"`
 	ConfigRaw json.RawMessage `json:"-"`
 	Config    interface{}     `json:"config,omitempty"`
 }
 
+// costConfigDTO accepts either a static cost ("cost": 5) or a rule set.
+type costConfigDTO struct {
+	Default int            `json:"default"`
+	Header  string         `json:"header"`
+	Methods map[string]int `json:"methods"`
+	Paths   []pathCostDTO  `json:"paths"`
+	Max     int            `json:"max"`
+}
+
+type pathCostDTO struct {
+	Prefix string `json:"prefix"`
+	Cost   int    `json:"cost"`
+}
+
+func (c *costConfigDTO) UnmarshalJSON(data []byte) error {
+	var static int
+	if err := json.Unmarshal(data, &static); err == nil {
+		c.Default = static
+		return nil
+	}
+
+	type alias costConfigDTO
+	var rules alias
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("cost must be a number or an object: %w", err)
+	}
+	*c = costConfigDTO(rules)
+	return nil
+}
+
+// keyConfigDTO accepts either a key template ("key": "header:X-API-Key") or an object.
+type keyConfigDTO struct {
+	Template string       `json:"template"`
+	JWT      jwtConfigDTO `json:"jwt"`
+	Missing  string       `json:"missing"`
+}
+
+type jwtConfigDTO struct {
+	Header    string `json:"header"`
+	Cookie    string `json:"cookie"`
+	Algorithm string `json:"algorithm"`
+	Secret    string `json:"secret"`
+	PublicKey string `json:"public_key"`
+}
+
+func (k *keyConfigDTO) UnmarshalJSON(data []byte) error {
+	var template string
+	if err := json.Unmarshal(data, &template); err == nil {
+		k.Template = template
+		return nil
+	}
+
+	type alias keyConfigDTO
+	var key alias
+	if err := json.Unmarshal(data, &key); err != nil {
+		return fmt.Errorf("key must be a string or an object: %w", err)
+	}
+	*k = keyConfigDTO(key)
+	return nil
+}
+
+// failureConfigDTO accepts either a failure mode ("failure": "open") or an object.
+type failureConfigDTO struct {
+	Mode          string  `json:"mode"`
+	LocalFraction float64 `json:"local_fraction"`
+	RetryAfter    int     `json:"retry_after"`
+}
+
+func (f *failureConfigDTO) UnmarshalJSON(data []byte) error {
+	var mode string
+	if err := json.Unmarshal(data, &mode); err == nil {
+		f.Mode = mode
+		return nil
+	}
+
+	type alias failureConfigDTO
+	var failure alias
+	if err := json.Unmarshal(data, &failure); err != nil {
+		return fmt.Errorf("failure must be a string or an object: %w", err)
+	}
+	*f = failureConfigDTO(failure)
+	return nil
+}
+
+type routeMatcherDTO struct {
+	Path    string   `json:"path"`
+	Prefix  string   `json:"prefix"`
+	Glob    string   `json:"glob"`
+	Regex   string   `json:"regex"`
+	Methods []string `json:"methods"`
+	Host    string   `json:"host"`
+}
+
+// matchersDTO accepts either a single matcher or a list of matchers. Unknown
+// fields are rejected, as a misspelled condition would leave a matcher
+// matching every request.
+type matchersDTO []routeMatcherDTO
+
+func (m *matchersDTO) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*m = nil
+		return nil
+	case bytes.HasPrefix(data, []byte("[")):
+		var list []routeMatcherDTO
+		if err := decodeStrict(data, &list); err != nil {
+			return fmt.Errorf("match must be an object or a list of objects: %w", err)
+		}
+		*m = list
+		return nil
+	default:
+		var single routeMatcherDTO
+		if err := decodeStrict(data, &single); err != nil {
+			return fmt.Errorf("match must be an object or a list of objects: %w", err)
+		}
+		*m = matchersDTO{single}
+		return nil
+	}
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
 type fixedWindowConfigDTO struct {
 	Limit  int `json:"limit"`
 	Window int `json:"window"`
@@ -49,18 +273,82 @@ type slidingWindowConfigDTO struct {
 	Window int `json:"window"`
 }
 
+type leakyBucketConfigDTO struct {
+	Capacity int `json:"capacity"`
+	LeakRate int `json:"leak_rate"`
+}
+
+type slidingWindowCounterConfigDTO struct {
+	Limit  int `json:"limit"`
+	Window int `json:"window"`
+}
+
+type concurrencyConfigDTO struct {
+	Limit    int `json:"limit"`
+	LeaseTTL int `json:"lease_ttl"`
+}
+
+type gcraConfigDTO struct {
+	Limit  int `json:"limit"`
+	Window int `json:"window"`
+	Burst  int `json:"burst"`
+}
+
 func (r *routeConfigDTO) UnmarshalJSON(data []byte) error {
-	type Alias routeConfigDTO
 	aux := struct {
-		Algorithm string          `json:"algorithm"`
-		Raw       json.RawMessage `json:"-"`
+		Algorithm string             `json:"algorithm"`
+		Limits    []limitConfigDTO   `json:"limits"`
+		Cost      *costConfigDTO     `json:"cost"`
+		Key       *keyConfigDTO      `json:"key"`
+		Match     matchersDTO        `json:"match"`
+		Failure   failureConfigDTO   `json:"failure"`
+		Penalty   penaltyConfigDTO   `json:"penalty"`
+		Access    accessConfigDTO    `json:"access"`
+		IPPrefix  ipPrefixConfigDTO  `json:"ip_prefix"`
+		Rejection rejectionConfigDTO `json:"rejection"`
+		Upstream  upstreamConfigDTO  `json:"upstream"`
 	}{}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
-	r.Algorithm = aux.Algorithm
-	r.ConfigRaw = data
+	if aux.Cost != nil {
+		r.Cost = *aux.Cost
+	}
+	if aux.Key != nil {
+		r.Key = *aux.Key
+	}
+	r.Match = aux.Match
+	r.Failure = aux.Failure
+	r.Penalty = aux.Penalty
+	r.Access = aux.Access
+	r.IPPrefix = aux.IPPrefix
+	r.Rejection = aux.Rejection
+	r.Upstream = aux.Upstream
+
+	r.Limits = aux.Limits
+	if len(r.Limits) == 0 && aux.Algorithm != "" {
+		var limit limitConfigDTO
+		if err := json.Unmarshal(data, &limit); err != nil {
+			return err
+		}
+		r.Limits = []limitConfigDTO{limit}
+	}
+	return nil
+}
+
+func (l *limitConfigDTO) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		Name      string `json:"name"`
+		Algorithm string `json:"algorithm"`
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	l.Name = aux.Name
+	l.Algorithm = aux.Algorithm
+	l.ConfigRaw = data
 
 	switch aux.Algorithm {
 	case domainConfig.AlgorithmFixedWindow:
@@ -68,58 +356,339 @@ func (r *routeConfigDTO) UnmarshalJSON(data []byte) error {
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			return err
 		}
-		r.Config = cfg
+		l.Config = cfg
 	case domainConfig.AlgorithmTokenBucket:
 		var cfg tokenBucketConfigDTO
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			return err
 		}
-		r.Config = cfg
+		l.Config = cfg
 	case domainConfig.AlgorithmSlidingWindow:
 		var cfg slidingWindowConfigDTO
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			return err
 		}
-		r.Config = cfg
+		l.Config = cfg
+	case domainConfig.AlgorithmLeakyBucket:
+		var cfg leakyBucketConfigDTO
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+		l.Config = cfg
+	case domainConfig.AlgorithmGCRA:
+		var cfg gcraConfigDTO
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+		l.Config = cfg
+	case domainConfig.AlgorithmSlidingWindowCounter:
+		var cfg slidingWindowCounterConfigDTO
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+		l.Config = cfg
+	case domainConfig.AlgorithmConcurrency:
+		var cfg concurrencyConfigDTO
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return err
+		}
+		l.Config = cfg
 	default:
-		r.Config = nil
+		l.Config = nil
 	}
 	return nil
 }
 
-func dtoToDomain(dto limiterConfigDTO) domainConfig.Config {
+func dtoToDomain(dto limiterConfigDTO) (domainConfig.Config, error) {
+	access, err := accessDTOToDomain(dto.Access)
+	if err != nil {
+		return domainConfig.Config{}, fmt.Errorf("access: %w", err)
+	}
+
 	cfg := domainConfig.Config{
-		Routes: make(map[string]domainConfig.RouteConfig),
+		Routes:       make(map[string]domainConfig.RouteConfig),
+		UnknownRoute: dto.UnknownRoute,
+		Failure:      failureDTOToDomain(dto.Failure),
+		Access:       access,
 	}
 
 	for route, routeDTO := range dto.Routes {
-		domainRoute := domainConfig.RouteConfig{
-			Algorithm: routeDTO.Algorithm,
+		domainRoute, err := routeDTOToDomain(routeDTO)
+		if err != nil {
+			return domainConfig.Config{}, fmt.Errorf("route %s: %w", route, err)
+		}
+		cfg.Routes[route] = domainRoute
+	}
+
+	if dto.Default != nil {
+		defaultRoute, err := routeDTOToDomain(*dto.Default)
+		if err != nil {
+			return domainConfig.Config{}, fmt.Errorf("default route: %w", err)
 		}
+		cfg.Default = &defaultRoute
+	}
 
-		switch c := routeDTO.Config.(type) {
-		case fixedWindowConfigDTO:
-			domainRoute.Config = domainConfig.FixedWindowConfig{
-				Limit:  c.Limit,
-				Window: c.Window,
-			}
-		case tokenBucketConfigDTO:
-			domainRoute.Config = domainConfig.TokenBucketConfig{
-				Capacity:   c.Capacity,
-				RefillRate: c.RefillRate,
-				BucketTTL:  c.BucketTTL,
-			}
-		case slidingWindowConfigDTO:
-			domainRoute.Config = domainConfig.SlidingWindowConfig{
-				Limit:  c.Limit,
-				Window: c.Window,
+	if err := cfg.Validate(); err != nil {
+		return domainConfig.Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func routeDTOToDomain(routeDTO routeConfigDTO) (domainConfig.RouteConfig, error) {
+	key, err := keyDTOToDomain(routeDTO.Key)
+	if err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	matchers, err := matchersDTOToDomain(routeDTO.Match)
+	if err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	failure := failureDTOToDomain(routeDTO.Failure)
+	if err := failure.Validate(); err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	penalty := penaltyDTOToDomain(routeDTO.Penalty)
+	if err := penalty.Validate(); err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	access, err := accessDTOToDomain(routeDTO.Access)
+	if err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	ipPrefix := domainConfig.IPPrefixConfig{IPv4: routeDTO.IPPrefix.IPv4, IPv6: routeDTO.IPPrefix.IPv6}
+	if err := ipPrefix.Validate(); err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	rejection := rejectionDTOToDomain(routeDTO.Rejection)
+	if err := rejection.Validate(); err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+
+	upstream := upstreamDTOToDomain(routeDTO.Upstream)
+	if err := upstream.Validate(); err != nil {
+		return domainConfig.RouteConfig{}, err
+	}
+	upstream.ID = upstream.Fingerprint()
+
+	domainRoute := domainConfig.RouteConfig{
+		Cost:      costDTOToDomain(routeDTO.Cost),
+		Key:       key,
+		Match:     matchers,
+		Failure:   failure,
+		Penalty:   penalty,
+		Access:    access,
+		IPPrefix:  ipPrefix,
+		Rejection: rejection,
+		Upstream:  upstream,
+	}
+
+	names := make(map[string]bool, len(routeDTO.Limits))
+	for i, limitDTO := range routeDTO.Limits {
+		name := limitDTO.Name
+		if name == "" {
+			name = strconv.Itoa(i)
+		}
+		// Stacked limits are keyed by name, so limits sharing one would share a counter
+		if names[name] {
+			return domainConfig.RouteConfig{}, fmt.Errorf("limit name %q is used by several limits of the route", name)
+		}
+		names[name] = true
+		domainRoute.Limits = append(domainRoute.Limits, domainConfig.LimitConfig{
+			Name:      name,
+			Algorithm: limitDTO.Algorithm,
+			Config:    algorithmConfigToDomain(limitDTO.Config),
+		})
+	}
+
+	return domainRoute, nil
+}
+
+func algorithmConfigToDomain(dto interface{}) domainConfig.AlgorithmConfig {
+	switch c := dto.(type) {
+	case fixedWindowConfigDTO:
+		return domainConfig.FixedWindowConfig{
+			Limit:  c.Limit,
+			Window: c.Window,
+		}
+	case tokenBucketConfigDTO:
+		return domainConfig.TokenBucketConfig{
+			Capacity:   c.Capacity,
+			RefillRate: c.RefillRate,
+			BucketTTL:  c.BucketTTL,
+		}
+	case slidingWindowConfigDTO:
+		return domainConfig.SlidingWindowConfig{
+			Limit:  c.Limit,
+			Window: c.Window,
+		}
+	case leakyBucketConfigDTO:
+		return domainConfig.LeakyBucketConfig{
+			Capacity: c.Capacity,
+			LeakRate: c.LeakRate,
+		}
+	case gcraConfigDTO:
+		return domainConfig.GCRAConfig{
+			Limit:  c.Limit,
+			Window: c.Window,
+			Burst:  c.Burst,
+		}
+	case slidingWindowCounterConfigDTO:
+		return domainConfig.SlidingWindowCounterConfig{
+			Limit:  c.Limit,
+			Window: c.Window,
+		}
+	case concurrencyConfigDTO:
+		return domainConfig.ConcurrencyConfig{
+			Limit:    c.Limit,
+			LeaseTTL: c.LeaseTTL,
+		}
+	default:
+		return nil
+	}
+}
+
+func costDTOToDomain(dto costConfigDTO) domainConfig.CostConfig {
+	cost := domainConfig.CostConfig{
+		Default: dto.Default,
+		Header:  dto.Header,
+		Max:     dto.Max,
+	}
+
+	if len(dto.Methods) > 0 {
+		cost.Methods = make(map[string]int, len(dto.Methods))
+		for method, c := range dto.Methods {
+			cost.Methods[strings.ToUpper(method)] = c
+		}
+	}
+
+	for _, p := range dto.Paths {
+		cost.Paths = append(cost.Paths, domainConfig.PathCost{Prefix: p.Prefix, Cost: p.Cost})
+	}
+
+	return cost
+}
+
+func keyDTOToDomain(dto keyConfigDTO) (domainConfig.KeyConfig, error) {
+	segments, err := domainConfig.ParseKeyTemplate(dto.Template)
+	if err != nil {
+		return domainConfig.KeyConfig{}, err
+	}
+
+	key := domainConfig.KeyConfig{
+		Segments: segments,
+		Missing:  dto.Missing,
+		JWT: domainConfig.JWTConfig{
+			Header:    dto.JWT.Header,
+			Cookie:    dto.JWT.Cookie,
+			Algorithm: strings.ToUpper(dto.JWT.Algorithm),
+			Secret:    dto.JWT.Secret,
+			PublicKey: dto.JWT.PublicKey,
+		},
+	}
+	if err := key.Validate(); err != nil {
+		return domainConfig.KeyConfig{}, err
+	}
+	return key, nil
+}
+
+func matchersDTOToDomain(dto matchersDTO) ([]domainConfig.RouteMatcher, error) {
+	matchers := make([]domainConfig.RouteMatcher, 0, len(dto))
+	for _, m := range dto {
+		matcher := domainConfig.RouteMatcher{
+			Path:   m.Path,
+			Prefix: m.Prefix,
+			Glob:   m.Glob,
+			Host:   m.Host,
+		}
+		for _, method := range m.Methods {
+			matcher.Methods = append(matcher.Methods, strings.ToUpper(method))
+		}
+		if m.Regex != "" {
+			re, err := regexp.Compile(m.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid match regex %q: %w", m.Regex, err)
 			}
-		default:
-			domainRoute.Config = nil
+			matcher.Regex = re
 		}
+		if err := matcher.Validate(); err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
 
-		cfg.Routes[route] = domainRoute
+func failureDTOToDomain(dto failureConfigDTO) domainConfig.FailureConfig {
+	return domainConfig.FailureConfig{
+		Mode:          strings.ToLower(dto.Mode),
+		LocalFraction: dto.LocalFraction,
+		RetryAfter:    dto.RetryAfter,
+	}
+}
+
+func penaltyDTOToDomain(dto penaltyConfigDTO) domainConfig.PenaltyConfig {
+	return domainConfig.PenaltyConfig{
+		Threshold:   dto.Threshold,
+		Window:      dto.Window,
+		Duration:    dto.Duration,
+		Multiplier:  dto.Multiplier,
+		MaxDuration: dto.MaxDuration,
 	}
+}
 
-	return cfg
+func accessDTOToDomain(dto accessConfigDTO) (domainConfig.AccessConfig, error) {
+	allow, err := domainConfig.ParseIPRanges(dto.Allow)
+	if err != nil {
+		return domainConfig.AccessConfig{}, fmt.Errorf("allow: %w", err)
+	}
+	deny, err := domainConfig.ParseIPRanges(dto.Deny)
+	if err != nil {
+		return domainConfig.AccessConfig{}, fmt.Errorf("deny: %w", err)
+	}
+	return domainConfig.AccessConfig{Allow: allow, Deny: deny}, nil
+}
+
+func upstreamDTOToDomain(dto upstreamConfigDTO) domainConfig.UpstreamConfig {
+	return domainConfig.UpstreamConfig{
+		Targets:  dto.Targets,
+		Balancer: dto.Balancer,
+		HealthCheck: domainConfig.HealthCheckConfig{
+			Path:               dto.HealthCheck.Path,
+			Interval:           dto.HealthCheck.Interval,
+			Timeout:            dto.HealthCheck.Timeout,
+			HealthyThreshold:   dto.HealthCheck.HealthyThreshold,
+			UnhealthyThreshold: dto.HealthCheck.UnhealthyThreshold,
+		},
+	}
+}
+
+func rejectionDTOToDomain(dto rejectionConfigDTO) domainConfig.RejectionConfig {
+	rejection := domainConfig.RejectionConfig{
+		Status:   dto.Status,
+		Headers:  dto.Headers,
+		Redirect: dto.Redirect,
+	}
+
+	bodies := dto.Bodies
+	if dto.Body != "" {
+		bodies = append([]rejectionBodyDTO{{ContentType: dto.ContentType, Body: dto.Body}}, bodies...)
+	}
+	for _, body := range bodies {
+		contentType := body.ContentType
+		if contentType == "" {
+			contentType = domainConfig.DefaultRejectionContentType
+		}
+		rejection.Bodies = append(rejection.Bodies, domainConfig.RejectionBody{
+			ContentType: contentType,
+			Template:    body.Body,
+		})
+	}
+	return rejection
 }
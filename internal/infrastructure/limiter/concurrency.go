@@ -0,0 +1,120 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/google/uuid"
+)
+
+const (
+	concurrencyActionAcquire = "acquire"
+	concurrencyActionRelease = "release"
+)
+
+// Positions of the lease arguments in an acquire call.
+const (
+	concurrencyLeaseIDArg = 4
+	concurrencyCostArg    = 5
+)
+
+// ConcurrencyLimiter limits the number of in-flight requests per key. Allowed
+// requests hold a lease that must be released when the request completes.
+type ConcurrencyLimiter struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+}
+
+func NewConcurrencyLimiter(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return &ConcurrencyLimiter{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+	}
+}
+
+func ConcurrencyLimiterFactory(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return NewConcurrencyLimiter(score, scriptSHA1)
+}
+
+func (c *ConcurrencyLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, c.score, c.scriptSHA1, c, key, cost, cfg)
+}
+
+func (c *ConcurrencyLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
+	concurrencyCfg, ok := cfg.(config.ConcurrencyConfig)
+	if !ok {
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for ConcurrencyLimiter, got %T", cfg)
+	}
+
+	now := time.Now().UnixMilli()
+	leaseID := uuid.New().String()
+	leaseTTLMs := concurrencyCfg.LeaseTTL * 1000
+
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{
+			concurrencyActionAcquire,
+			concurrencyCfg.Limit,
+			leaseTTLMs,
+			now,
+			leaseID,
+			cost,
+		},
+	}, nil
+}
+
+func (c *ConcurrencyLimiter) ParseResult(call ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	concurrencyCfg, ok := cfg.(config.ConcurrencyConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for ConcurrencyLimiter, got %T", cfg)
+	}
+
+	result, ok := res.([]interface{})
+	if !ok || len(result) < 4 {
+		return ports.RateLimitInfo{}, fmt.Errorf("unexpected lua script response")
+	}
+
+	allowed, _ := result[0].(int64)
+	remaining, _ := result[2].(int64)
+	resetTime, _ := result[3].(int64)
+
+	info := ports.RateLimitInfo{
+		Allowed:   allowed == 1,
+		Limit:     concurrencyCfg.Limit,
+		Remaining: int(remaining),
+		ResetTime: resetTime,
+	}
+	if info.Allowed {
+		leaseID, _ := call.Args[concurrencyLeaseIDArg].(string)
+		cost, _ := call.Args[concurrencyCostArg].(int)
+		info.Lease = &concurrencyLease{
+			score:      c.score,
+			scriptSHA1: c.scriptSHA1,
+			key:        call.Keys[0],
+			leaseID:    leaseID,
+			cost:       cost,
+		}
+	}
+
+	return info, nil
+}
+
+type concurrencyLease struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+	key        string
+	leaseID    string
+	cost       int
+}
+
+func (l *concurrencyLease) Release(ctx context.Context) error {
+	_, err := l.score.EvalSha(ctx, l.scriptSHA1, []string{l.key}, []interface{}{
+		concurrencyActionRelease,
+		l.leaseID,
+		l.cost,
+	})
+	return err
+}
@@ -25,15 +25,26 @@ func FixedWindowLimiterFactory(score ports.LimiterScore, scriptSHA1 string) port
 	return NewFixedWindowLimiter(score, scriptSHA1)
 }
 
-func (f *FixedWindowLimiter) Allow(ctx context.Context, key string, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+func (f *FixedWindowLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, f.score, f.scriptSHA1, f, key, cost, cfg)
+}
+
+func (f *FixedWindowLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
 	fixedCfg, ok := cfg.(config.FixedWindowConfig)
 	if !ok {
-		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for FixedWindowLimiter, got %T", cfg)
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for FixedWindowLimiter, got %T", cfg)
 	}
 
-	res, err := f.score.EvalSha(ctx, f.scriptSHA1, []string{key}, []interface{}{fixedCfg.Window, fixedCfg.Limit})
-	if err != nil {
-		return ports.RateLimitInfo{}, err
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{fixedCfg.Window, fixedCfg.Limit, cost},
+	}, nil
+}
+
+func (f *FixedWindowLimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	fixedCfg, ok := cfg.(config.FixedWindowConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for FixedWindowLimiter, got %T", cfg)
 	}
 
 	result, ok := res.([]interface{})
@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// GCRALimiter implements the generic cell rate algorithm. It keeps a single
+// theoretical arrival time per key, giving sliding window accuracy with O(1) memory.
+type GCRALimiter struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+}
+
+func NewGCRALimiter(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return &GCRALimiter{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+	}
+}
+
+func GCRALimiterFactory(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return NewGCRALimiter(score, scriptSHA1)
+}
+
+func (g *GCRALimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, g.score, g.scriptSHA1, g, key, cost, cfg)
+}
+
+func (g *GCRALimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
+	gcraCfg, ok := cfg.(config.GCRAConfig)
+	if !ok {
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for GCRALimiter, got %T", cfg)
+	}
+
+	now := time.Now().UnixMilli()
+	emissionInterval := float64(gcraCfg.Window*1000) / float64(gcraCfg.Limit)
+
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{
+			emissionInterval,
+			gcraCfg.EffectiveBurst(),
+			now,
+			cost,
+		},
+	}, nil
+}
+
+func (g *GCRALimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	gcraCfg, ok := cfg.(config.GCRAConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for GCRALimiter, got %T", cfg)
+	}
+
+	result, ok := res.([]interface{})
+	if !ok || len(result) < 4 {
+		return ports.RateLimitInfo{}, fmt.Errorf("unexpected lua script response")
+	}
+
+	allowed, _ := result[0].(int64)
+	remaining, _ := result[2].(int64)
+	resetTime, _ := result[3].(int64)
+
+	return ports.RateLimitInfo{
+		Allowed:   allowed == 1,
+		Limit:     gcraCfg.EffectiveBurst(),
+		Remaining: int(remaining),
+		ResetTime: resetTime,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// LeakyBucketLimiter queues requests in a bucket that drains at a constant rate.
+// Accepted requests carry the Delay until their slot in the queue drains, so the
+// outflow towards the backend never exceeds LeakRate requests per second.
+type LeakyBucketLimiter struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+}
+
+func NewLeakyBucketLimiter(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return &LeakyBucketLimiter{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+	}
+}
+
+func LeakyBucketLimiterFactory(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return NewLeakyBucketLimiter(score, scriptSHA1)
+}
+
+func (l *LeakyBucketLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, l.score, l.scriptSHA1, l, key, cost, cfg)
+}
+
+func (l *LeakyBucketLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
+	leakyCfg, ok := cfg.(config.LeakyBucketConfig)
+	if !ok {
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for LeakyBucketLimiter, got %T", cfg)
+	}
+
+	now := time.Now().UnixMilli()
+
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{
+			leakyCfg.Capacity,
+			leakyCfg.LeakRate,
+			now,
+			cost,
+		},
+	}, nil
+}
+
+func (l *LeakyBucketLimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	leakyCfg, ok := cfg.(config.LeakyBucketConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for LeakyBucketLimiter, got %T", cfg)
+	}
+
+	result, ok := res.([]interface{})
+	if !ok || len(result) < 5 {
+		return ports.RateLimitInfo{}, fmt.Errorf("unexpected lua script response")
+	}
+
+	allowed, _ := result[0].(int64)
+	remaining, _ := result[2].(int64)
+	resetTime, _ := result[3].(int64)
+	delayMs, _ := result[4].(int64)
+
+	return ports.RateLimitInfo{
+		Allowed:   allowed == 1,
+		Limit:     leakyCfg.Capacity,
+		Remaining: int(remaining),
+		ResetTime: resetTime,
+		Delay:     time.Duration(delayMs) * time.Millisecond,
+	}, nil
+}
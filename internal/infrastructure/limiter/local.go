@@ -0,0 +1,182 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// localSweepInterval is how often idle local buckets are dropped.
+const localSweepInterval = time.Minute
+
+// LocalLimiter is the in-process fallback used while Redis is unavailable.
+// Rate limits are approximated by token buckets refilling Quota.Limit units
+// every Quota.Period, and in-flight limits by counters released through leases.
+type LocalLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*localBucket
+	inFlight  map[string]int
+	lastSweep time.Time
+}
+
+type localBucket struct {
+	tokens  float64
+	limit   float64
+	rate    float64 // tokens per second
+	updated time.Time
+}
+
+func NewLocalLimiter() ports.LocalRateLimiter {
+	return &LocalLimiter{
+		buckets:   make(map[string]*localBucket),
+		inFlight:  make(map[string]int),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *LocalLimiter) AllowAll(checks []ports.LimitCheck, fraction float64) []ports.RateLimitInfo {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	quotas := make([]config.Quota, len(checks))
+	infos := make([]ports.RateLimitInfo, len(checks))
+	allowed := true
+	for i, check := range checks {
+		quotas[i] = check.Config.Quota().Scale(fraction)
+		infos[i] = l.check(check.Key, check.Cost, quotas[i], now)
+		allowed = allowed && infos[i].Allowed
+	}
+
+	if !allowed {
+		return infos
+	}
+
+	for i, check := range checks {
+		infos[i] = l.consume(check.Key, check.Cost, quotas[i], now)
+	}
+	return infos
+}
+
+// check reports whether cost fits in the limit without consuming it.
+func (l *LocalLimiter) check(key string, cost int, quota config.Quota, now time.Time) ports.RateLimitInfo {
+	if quota.InFlight {
+		inFlight := l.inFlight[key]
+		return ports.RateLimitInfo{
+			Allowed:   inFlight+cost <= quota.Limit,
+			Limit:     quota.Limit,
+			Remaining: max(quota.Limit-inFlight, 0),
+		}
+	}
+
+	bucket := l.bucket(key, quota, now)
+	info := ports.RateLimitInfo{
+		Allowed:   bucket.tokens >= float64(cost),
+		Limit:     quota.Limit,
+		Remaining: int(bucket.tokens),
+		ResetTime: bucket.fullAt(now).Unix(),
+	}
+	if !info.Allowed && bucket.rate > 0 {
+		info.RetryAfter = time.Duration((float64(cost) - bucket.tokens) / bucket.rate * float64(time.Second))
+	}
+	return info
+}
+
+func (l *LocalLimiter) consume(key string, cost int, quota config.Quota, now time.Time) ports.RateLimitInfo {
+	if quota.InFlight {
+		l.inFlight[key] += cost
+		return ports.RateLimitInfo{
+			Allowed:   true,
+			Limit:     quota.Limit,
+			Remaining: max(quota.Limit-l.inFlight[key], 0),
+			Lease:     &localLease{limiter: l, key: key, cost: cost},
+		}
+	}
+
+	bucket := l.bucket(key, quota, now)
+	bucket.tokens -= float64(cost)
+	return ports.RateLimitInfo{
+		Allowed:   true,
+		Limit:     quota.Limit,
+		Remaining: int(bucket.tokens),
+		ResetTime: bucket.fullAt(now).Unix(),
+	}
+}
+
+// bucket returns the refilled bucket of key, creating a full one if needed.
+func (l *LocalLimiter) bucket(key string, quota config.Quota, now time.Time) *localBucket {
+	limit := float64(quota.Limit)
+	var rate float64
+	if quota.Period > 0 {
+		rate = limit / quota.Period.Seconds()
+	}
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &localBucket{tokens: limit, updated: now}
+		l.buckets[key] = bucket
+	}
+	// Limits may change with the configuration, so refresh them on every use.
+	bucket.limit = limit
+	bucket.rate = rate
+
+	bucket.tokens = math.Min(limit, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+	return bucket
+}
+
+// sweep drops buckets that have refilled completely, as they hold no state.
+func (l *LocalLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < localSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, bucket := range l.buckets {
+		if !bucket.fullAt(now).After(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *LocalLimiter) release(key string, cost int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight[key] -= cost
+	if l.inFlight[key] <= 0 {
+		delete(l.inFlight, key)
+	}
+}
+
+// fullAt returns when the bucket will have refilled completely.
+func (b *localBucket) fullAt(now time.Time) time.Time {
+	if b.rate <= 0 || b.tokens >= b.limit {
+		return now
+	}
+	elapsed := now.Sub(b.updated).Seconds()
+	missing := b.limit - math.Min(b.limit, b.tokens+elapsed*b.rate)
+	return now.Add(time.Duration(missing / b.rate * float64(time.Second)))
+}
+
+// localLease frees an in-flight slot of the local limiter.
+type localLease struct {
+	limiter  *LocalLimiter
+	key      string
+	cost     int
+	released sync.Once
+}
+
+func (l *localLease) Release(_ context.Context) error {
+	l.released.Do(func() {
+		l.limiter.release(l.key, l.cost)
+	})
+	return nil
+}
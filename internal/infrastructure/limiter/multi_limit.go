@@ -0,0 +1,106 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// MultiLimiter evaluates stacked limits in a single Lua script, so a request
+// rejected by one limit does not consume the budget of the others.
+type MultiLimiter struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+	limiters   map[string]ports.RateLimiter
+}
+
+func NewMultiLimiter(score ports.LimiterScore, scriptSHA1 string, limiters map[string]ports.RateLimiter) ports.MultiRateLimiter {
+	return &MultiLimiter{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+		limiters:   limiters,
+	}
+}
+
+// BuildMultiLimitScript wraps every algorithm script into a function of the
+// `algorithms` table and prepends them to the multi limit script body.
+func BuildMultiLimitScript(body string, algorithmScripts map[string]string) string {
+	names := make([]string, 0, len(algorithmScripts))
+	for name := range algorithmScripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("local algorithms = {}\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "algorithms[%q] = function(KEYS, ARGV)\n%s\nend\n", name, algorithmScripts[name])
+	}
+	b.WriteString(body)
+	return b.String()
+}
+
+func (m *MultiLimiter) AllowAll(ctx context.Context, checks []ports.LimitCheck) ([]ports.RateLimitInfo, error) {
+	return m.evaluate(ctx, checks, dryRunOff)
+}
+
+func (m *MultiLimiter) CheckAll(ctx context.Context, checks []ports.LimitCheck) ([]ports.RateLimitInfo, error) {
+	return m.evaluate(ctx, checks, dryRunOn)
+}
+
+// evaluate runs the multi limit script, only checking the limits when dryRun is
+// dryRunOn.
+func (m *MultiLimiter) evaluate(ctx context.Context, checks []ports.LimitCheck, dryRun string) ([]ports.RateLimitInfo, error) {
+	scripted := make([]ports.ScriptedRateLimiter, len(checks))
+	calls := make([]ports.ScriptCall, len(checks))
+
+	header := []interface{}{len(checks)}
+	var keys []string
+	var args []interface{}
+
+	for i, check := range checks {
+		limiter, ok := m.limiters[check.Algorithm].(ports.ScriptedRateLimiter)
+		if !ok {
+			return nil, fmt.Errorf("algorithm '%s' cannot be stacked", check.Algorithm)
+		}
+
+		call, err := limiter.BuildCall(check.Key, check.Cost, check.Config)
+		if err != nil {
+			return nil, err
+		}
+
+		scripted[i] = limiter
+		calls[i] = call
+
+		// The extra argument is the dry-run flag, set by the script for each pass
+		// unless checking only
+		header = append(header, check.Algorithm, len(call.Keys), len(call.Args)+1)
+		keys = append(keys, call.Keys...)
+		args = append(args, call.Args...)
+		args = append(args, dryRun)
+	}
+
+	res, err := m.score.EvalSha(ctx, m.scriptSHA1, keys, header, args)
+	if err != nil {
+		return nil, err
+	}
+
+	results, ok := res.([]interface{})
+	if !ok || len(results) != len(checks) {
+		return nil, fmt.Errorf("unexpected lua script response")
+	}
+
+	infos := make([]ports.RateLimitInfo, len(checks))
+	for i, check := range checks {
+		info, err := scripted[i].ParseResult(calls[i], results[i], check.Config)
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+
+	return infos, nil
+}
@@ -0,0 +1,219 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+const (
+	penaltyActionCheck  = "check"
+	penaltyActionReject = "reject"
+
+	// Penalty keys hash tag the route and client, so the keys of a client land
+	// in the same Redis Cluster slot for the penalty script.
+	banKeyPrefix        = "pb:ban:{%s:%s}"
+	rejectionsKeyPrefix = "pb:rejections:{%s:%s}"
+	strikesKeyPrefix    = "pb:strikes:{%s:%s}"
+)
+
+// Local caching of penalty box lookups. Bans are cached for at most
+// banCacheTTL so bans lifted on another instance expire here soon after, and
+// clients found not banned are cached for notBannedCacheTTL so bans started on
+// another instance apply here within that delay.
+const (
+	banCacheTTL       = 5 * time.Second
+	notBannedCacheTTL = time.Second
+	banSweepInterval  = time.Minute
+)
+
+// PenaltyBox keeps bans in the limiter store, shared by every instance, and
+// caches lookups locally so banned clients cost no store round trip.
+type PenaltyBox struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+
+	mu        sync.Mutex
+	cache     map[banCacheKey]cachedBan
+	lastSweep time.Time
+}
+
+type banCacheKey struct {
+	route     string
+	clientKey string
+}
+
+type cachedBan struct {
+	ban       ports.Ban
+	banned    bool
+	readAt    time.Time // when the remaining ban was read
+	expiresAt time.Time
+}
+
+func NewPenaltyBox(score ports.LimiterScore, scriptSHA1 string) ports.PenaltyBox {
+	return &PenaltyBox{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+		cache:      make(map[banCacheKey]cachedBan),
+		lastSweep:  time.Now(),
+	}
+}
+
+func (p *PenaltyBox) Check(ctx context.Context, route, clientKey string) (ports.Ban, bool, error) {
+	if ban, banned, ok := p.cached(route, clientKey); ok {
+		return ban, banned, nil
+	}
+
+	ban, banned, _, err := p.eval(ctx, route, clientKey, penaltyActionCheck)
+	if err != nil {
+		return ports.Ban{}, false, err
+	}
+	p.remember(ban, banned)
+	return ban, banned, nil
+}
+
+func (p *PenaltyBox) RecordRejection(ctx context.Context, route, clientKey string, penalty config.PenaltyConfig) (ports.Ban, bool, error) {
+	ban, banned, started, err := p.eval(ctx, route, clientKey, penaltyActionReject,
+		penalty.Threshold,
+		penalty.Window*1000,
+		penalty.Duration*1000,
+		penalty.GrowthFactor(),
+		penalty.MaxDuration*1000,
+	)
+	if err != nil {
+		return ports.Ban{}, false, err
+	}
+	p.remember(ban, banned)
+	return ban, started, nil
+}
+
+func (p *PenaltyBox) List(ctx context.Context, route string) ([]ports.Ban, error) {
+	prefix := strings.TrimSuffix(fmt.Sprintf(banKeyPrefix, route, ""), "}")
+	keys, err := p.score.Keys(ctx, ports.EscapePattern(prefix)+"*}")
+	if err != nil {
+		return nil, err
+	}
+
+	bans := make([]ports.Ban, 0, len(keys))
+	for _, key := range keys {
+		state, err := p.score.Inspect(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if state.Type == ports.KeyTypeNone {
+			continue
+		}
+		strike, _ := strconv.Atoi(state.Value)
+		bans = append(bans, ports.Ban{
+			Route:     route,
+			ClientKey: strings.TrimSuffix(strings.TrimPrefix(key, prefix), "}"),
+			Remaining: state.TTL,
+			Strike:    strike,
+		})
+	}
+	return bans, nil
+}
+
+func (p *PenaltyBox) Remove(ctx context.Context, route, clientKey string) (bool, error) {
+	deleted, err := p.score.Delete(ctx,
+		fmt.Sprintf(banKeyPrefix, route, clientKey),
+		fmt.Sprintf(rejectionsKeyPrefix, route, clientKey),
+		fmt.Sprintf(strikesKeyPrefix, route, clientKey),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	p.mu.Lock()
+	delete(p.cache, banCacheKey{route: route, clientKey: clientKey})
+	p.mu.Unlock()
+	return deleted > 0, nil
+}
+
+// eval runs the penalty script, returning the ban of the client, whether it is
+// banned and whether this call started the ban.
+func (p *PenaltyBox) eval(ctx context.Context, route, clientKey, action string, args ...interface{}) (ports.Ban, bool, bool, error) {
+	keys := []string{
+		fmt.Sprintf(banKeyPrefix, route, clientKey),
+		fmt.Sprintf(rejectionsKeyPrefix, route, clientKey),
+		fmt.Sprintf(strikesKeyPrefix, route, clientKey),
+	}
+
+	res, err := p.score.EvalSha(ctx, p.scriptSHA1, keys, append([]interface{}{action}, args...))
+	if err != nil {
+		return ports.Ban{}, false, false, err
+	}
+
+	result, ok := res.([]interface{})
+	if !ok || len(result) < 4 {
+		return ports.Ban{}, false, false, fmt.Errorf("unexpected penalty script response")
+	}
+	banned, _ := result[0].(int64)
+	remaining, _ := result[1].(int64)
+	strike, _ := result[2].(int64)
+	started, _ := result[3].(int64)
+
+	ban := ports.Ban{
+		Route:     route,
+		ClientKey: clientKey,
+		Remaining: time.Duration(remaining) * time.Millisecond,
+		Strike:    int(strike),
+	}
+	return ban, banned == 1, started == 1, nil
+}
+
+func (p *PenaltyBox) cached(route, clientKey string) (ports.Ban, bool, bool) {
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sweep(now)
+
+	entry, ok := p.cache[banCacheKey{route: route, clientKey: clientKey}]
+	if !ok || !now.Before(entry.expiresAt) {
+		return ports.Ban{}, false, false
+	}
+
+	ban := entry.ban
+	ban.Remaining -= now.Sub(entry.readAt)
+	return ban, entry.banned, true
+}
+
+func (p *PenaltyBox) remember(ban ports.Ban, banned bool) {
+	now := time.Now()
+	ttl := notBannedCacheTTL
+	if banned {
+		ttl = min(ban.Remaining, banCacheTTL)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.cache[banCacheKey{route: ban.Route, clientKey: ban.ClientKey}] = cachedBan{
+		ban:       ban,
+		banned:    banned,
+		readAt:    now,
+		expiresAt: now.Add(ttl),
+	}
+}
+
+// sweep drops expired cache entries.
+func (p *PenaltyBox) sweep(now time.Time) {
+	if now.Sub(p.lastSweep) < banSweepInterval {
+		return
+	}
+	p.lastSweep = now
+
+	for key, entry := range p.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(p.cache, key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package limiter
+
+import (
+	"context"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// Values of the trailing dry-run flag every limiter script accepts.
+const (
+	dryRunOff = "0"
+	dryRunOn  = "1"
+)
+
+// evalScript runs a single limiter script call, consuming cost when allowed.
+func evalScript(ctx context.Context, score ports.LimiterScore, scriptSHA1 string,
+	limiter ports.ScriptedRateLimiter, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	call, err := limiter.BuildCall(key, cost, cfg)
+	if err != nil {
+		return ports.RateLimitInfo{}, err
+	}
+
+	args := append(append([]interface{}{}, call.Args...), dryRunOff)
+	res, err := score.EvalSha(ctx, scriptSHA1, call.Keys, args)
+	if err != nil {
+		return ports.RateLimitInfo{}, err
+	}
+
+	return limiter.ParseResult(call, res, cfg)
+}
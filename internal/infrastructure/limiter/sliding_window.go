@@ -24,25 +24,32 @@ func SlidingWindowLimiterFactory(score ports.LimiterScore, scriptSHA1 string) po
 	return NewSlidingWindowLimiter(score, scriptSHA1)
 }
 
-func (s *SlidingWindowLimiter) Allow(ctx context.Context, key string, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
-	slidingConfig, ok := cfg.(config.SlidingWindowConfig)
-	if !ok {
-		return ports.RateLimitInfo{}, errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
-			"invalid config type for SlidingWindowLimiter",
-			fmt.Errorf("invalid config type for SlidingWindowLimiter, got %T", cfg))
+func (s *SlidingWindowLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, s.score, s.scriptSHA1, s, key, cost, cfg)
+}
+
+func (s *SlidingWindowLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
+	slidingConfig, err := s.config(cfg)
+	if err != nil {
+		return ports.ScriptCall{}, err
 	}
 
 	now := time.Now().UnixMilli()
 	requestID := uuid.New().String()
 	windowMs := slidingConfig.Window * 1000
 
-	res, err := s.score.EvalSha(ctx,
-		s.scriptSHA1,
-		[]string{key}, []interface{}{windowMs, slidingConfig.Limit, now, requestID},
-	)
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{windowMs, slidingConfig.Limit, now, requestID, cost},
+	}, nil
+}
+
+func (s *SlidingWindowLimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	slidingConfig, err := s.config(cfg)
 	if err != nil {
 		return ports.RateLimitInfo{}, err
 	}
+
 	result, ok := res.([]interface{})
 	if !ok || len(result) < 4 {
 		return ports.RateLimitInfo{}, fmt.Errorf("unexpected lua script response")
@@ -59,3 +66,13 @@ func (s *SlidingWindowLimiter) Allow(ctx context.Context, key string, cfg config
 		ResetTime: resetTime,
 	}, nil
 }
+
+func (s *SlidingWindowLimiter) config(cfg config.AlgorithmConfig) (config.SlidingWindowConfig, error) {
+	slidingConfig, ok := cfg.(config.SlidingWindowConfig)
+	if !ok {
+		return config.SlidingWindowConfig{}, errors.NewRateLimiterError(errors.ErrInvalidConfig.Code,
+			"invalid config type for SlidingWindowLimiter",
+			fmt.Errorf("invalid config type for SlidingWindowLimiter, got %T", cfg))
+	}
+	return slidingConfig, nil
+}
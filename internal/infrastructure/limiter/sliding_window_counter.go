@@ -0,0 +1,74 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// SlidingWindowCounterLimiter approximates a sliding window using the counters
+// of the current and previous fixed windows, so each client only needs two keys.
+type SlidingWindowCounterLimiter struct {
+	score      ports.LimiterScore
+	scriptSHA1 string
+}
+
+func NewSlidingWindowCounterLimiter(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return &SlidingWindowCounterLimiter{
+		score:      score,
+		scriptSHA1: scriptSHA1,
+	}
+}
+
+func SlidingWindowCounterLimiterFactory(score ports.LimiterScore, scriptSHA1 string) ports.RateLimiter {
+	return NewSlidingWindowCounterLimiter(score, scriptSHA1)
+}
+
+func (s *SlidingWindowCounterLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, s.score, s.scriptSHA1, s, key, cost, cfg)
+}
+
+func (s *SlidingWindowCounterLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
+	counterCfg, ok := cfg.(config.SlidingWindowCounterConfig)
+	if !ok {
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for SlidingWindowCounterLimiter, got %T", cfg)
+	}
+
+	now := time.Now().UnixMilli()
+	windowMs := int64(counterCfg.Window) * 1000
+	currentWindow := now / windowMs
+
+	return ports.ScriptCall{
+		Keys: []string{
+			fmt.Sprintf("%s:%d", key, currentWindow),
+			fmt.Sprintf("%s:%d", key, currentWindow-1),
+		},
+		Args: []interface{}{windowMs, counterCfg.Limit, now, cost},
+	}, nil
+}
+
+func (s *SlidingWindowCounterLimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	counterCfg, ok := cfg.(config.SlidingWindowCounterConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for SlidingWindowCounterLimiter, got %T", cfg)
+	}
+
+	result, ok := res.([]interface{})
+	if !ok || len(result) < 4 {
+		return ports.RateLimitInfo{}, fmt.Errorf("unexpected lua script response")
+	}
+
+	allowed, _ := result[0].(int64)
+	remaining, _ := result[2].(int64)
+	resetTime, _ := result[3].(int64)
+
+	return ports.RateLimitInfo{
+		Allowed:   allowed == 1,
+		Limit:     counterCfg.Limit,
+		Remaining: int(remaining),
+		ResetTime: resetTime,
+	}, nil
+}
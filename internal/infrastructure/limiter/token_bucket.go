@@ -25,24 +25,34 @@ func TokenBucketLimiterFactory(score ports.LimiterScore, scriptSHA1 string) port
 	return NewTokenBucketLimiter(score, scriptSHA1)
 }
 
-func (t *TokenBucketLimiter) Allow(ctx context.Context, key string, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+func (t *TokenBucketLimiter) Allow(ctx context.Context, key string, cost int, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	return evalScript(ctx, t.score, t.scriptSHA1, t, key, cost, cfg)
+}
+
+func (t *TokenBucketLimiter) BuildCall(key string, cost int, cfg config.AlgorithmConfig) (ports.ScriptCall, error) {
 	tokenCfg, ok := cfg.(config.TokenBucketConfig)
 	if !ok {
-		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for TokenBucketLimiter, got %T", cfg)
+		return ports.ScriptCall{}, fmt.Errorf("invalid config type for TokenBucketLimiter, got %T", cfg)
 	}
 
 	now := time.Now().Unix()
-	tokensToConsume := 1
-
-	res, err := t.score.EvalSha(ctx, t.scriptSHA1, []string{key}, []interface{}{
-		tokenCfg.Capacity,
-		tokenCfg.RefillRate,
-		tokensToConsume,
-		now,
-		tokenCfg.BucketTTL,
-	})
-	if err != nil {
-		return ports.RateLimitInfo{}, err
+
+	return ports.ScriptCall{
+		Keys: []string{key},
+		Args: []interface{}{
+			tokenCfg.Capacity,
+			tokenCfg.RefillRate,
+			cost,
+			now,
+			tokenCfg.BucketTTL,
+		},
+	}, nil
+}
+
+func (t *TokenBucketLimiter) ParseResult(_ ports.ScriptCall, res interface{}, cfg config.AlgorithmConfig) (ports.RateLimitInfo, error) {
+	tokenCfg, ok := cfg.(config.TokenBucketConfig)
+	if !ok {
+		return ports.RateLimitInfo{}, fmt.Errorf("invalid config type for TokenBucketLimiter, got %T", cfg)
 	}
 
 	result, ok := res.([]interface{})
@@ -0,0 +1,430 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// Names of the scripts given to NewMemoryStore that are not limiter algorithms
+const (
+	MultiLimitScript = "multi_limit"
+	PenaltyScript    = "penalty"
+)
+
+// sweepInterval is how often expired keys are dropped.
+const sweepInterval = time.Minute
+
+var (
+	ErrKeyNotFound = errors.New("memory store: key not found")
+	ErrNoScript    = errors.New("memory store: no native implementation for script")
+	ErrWrongType   = errors.New("memory store: operation against a key holding the wrong kind of value")
+)
+
+// MemoryStore is a ports.LimiterScore keeping limiter state in process memory,
+// for single instance deployments and running without Redis. Lua scripts are
+// not interpreted: each supported script runs a native Go implementation with
+// the same keys, arguments, results and key expiry.
+type MemoryStore struct {
+	mu      sync.Mutex
+	data    *keyspace
+	scripts map[string]nativeScript
+	logger  ports.Logger
+}
+
+// NewMemoryStore creates a store able to run the given scripts, keyed by
+// algorithm name, MultiLimitScript or PenaltyScript and holding the Lua source that
+// ScriptLoad will be called with.
+func NewMemoryStore(logger ports.Logger, scripts map[string]string) (ports.LimiterScore, error) {
+	store := &MemoryStore{
+		data:    newKeyspace(),
+		scripts: make(map[string]nativeScript, len(scripts)),
+		logger:  logger,
+	}
+
+	for name, source := range scripts {
+		native, ok := nativeScripts[name]
+		switch name {
+		case MultiLimitScript:
+			native, ok = multiLimit, true
+		case PenaltyScript:
+			native, ok = penalty, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNoScript, name)
+		}
+		store.scripts[scriptSHA1(source)] = native
+	}
+	return store, nil
+}
+
+func (m *MemoryStore) Get(_ context.Context, key string) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.data.number(key)
+	if !ok {
+		return ErrKeyNotFound
+	}
+	return formatNumber(value)
+}
+
+func (m *MemoryStore) Set(_ context.Context, key string, value interface{}, ttlSeconds int) error {
+	n, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+	if err != nil {
+		return fmt.Errorf("memory store: value of %s is not a number: %w", key, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.setNumber(key, n, time.Duration(ttlSeconds)*time.Second)
+	return nil
+}
+
+func (m *MemoryStore) Incr(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.data.incrBy(key, 1)
+	return err
+}
+
+func (m *MemoryStore) Eval(ctx context.Context, script string, keys []string, args ...[]interface{}) (interface{}, error) {
+	return m.EvalSha(ctx, scriptSHA1(script), keys, args...)
+}
+
+func (m *MemoryStore) ScriptLoad(_ context.Context, script string) (string, error) {
+	sha := scriptSHA1(script)
+	if _, ok := m.scripts[sha]; !ok {
+		return "", ErrNoScript
+	}
+	m.logger.Info("Lua script bound to native implementation", ports.Field{Key: "sha1", Val: sha})
+	return sha, nil
+}
+
+func (m *MemoryStore) EvalSha(_ context.Context, sha1 string, keys []string, args ...[]interface{}) (interface{}, error) {
+	native, ok := m.scripts[sha1]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoScript, sha1)
+	}
+
+	flatArgs := make([]string, 0)
+	for _, arr := range args {
+		for _, arg := range arr {
+			flatArgs = append(flatArgs, formatArg(arg))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.data.sweep(time.Now())
+	return native(m.data, keys, flatArgs)
+}
+
+func (m *MemoryStore) Inspect(_ context.Context, key string) (ports.KeyState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state := ports.KeyState{Key: key, Type: ports.KeyTypeNone, TTL: -1}
+	e := m.data.get(key)
+	if e == nil {
+		return state, nil
+	}
+	if !e.expiresAt.IsZero() {
+		state.TTL = time.Until(e.expiresAt)
+	}
+
+	switch v := e.value.(type) {
+	case float64:
+		state.Type = ports.KeyTypeString
+		state.Value = formatNumber(v)
+	case map[string]float64:
+		state.Type = ports.KeyTypeHash
+		state.Fields = make(map[string]string, len(v))
+		for field, value := range v {
+			state.Fields[field] = formatNumber(value)
+		}
+	case *sortedSet:
+		state.Type = ports.KeyTypeSortedSet
+		for _, member := range v.members {
+			state.Members = append(state.Members, ports.ScoredMember{Member: member.name, Score: member.score})
+		}
+	}
+	return state, nil
+}
+
+func (m *MemoryStore) Keys(_ context.Context, pattern string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0)
+	for key := range m.data.entries {
+		if m.data.get(key) != nil && matchPattern(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (m *MemoryStore) Delete(_ context.Context, keys ...string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for _, key := range keys {
+		if m.data.get(key) != nil {
+			delete(m.data.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func scriptSHA1(script string) string {
+	sum := sha1.Sum([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+// formatArg formats a script argument the way the Redis client sends it.
+func formatArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// keyspace holds numbers, hashes and sorted sets with Redis like expiry.
+// It is not safe for concurrent use.
+type keyspace struct {
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+type entry struct {
+	value     interface{} // float64, map[string]float64 or *sortedSet
+	expiresAt time.Time   // zero means no expiry
+}
+
+func newKeyspace() *keyspace {
+	return &keyspace{
+		entries:   make(map[string]*entry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (k *keyspace) get(key string) *entry {
+	e, ok := k.entries[key]
+	if !ok {
+		return nil
+	}
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(k.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (k *keyspace) number(key string) (float64, bool) {
+	e := k.get(key)
+	if e == nil {
+		return 0, false
+	}
+	n, ok := e.value.(float64)
+	return n, ok
+}
+
+// setNumber replaces the value of key, expiring it after ttl unless ttl is zero.
+func (k *keyspace) setNumber(key string, n float64, ttl time.Duration) {
+	e := &entry{value: n}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	k.entries[key] = e
+}
+
+// incrBy increments the number of key, keeping its expiry.
+func (k *keyspace) incrBy(key string, delta float64) (float64, error) {
+	e := k.get(key)
+	if e == nil {
+		k.entries[key] = &entry{value: delta}
+		return delta, nil
+	}
+	n, ok := e.value.(float64)
+	if !ok {
+		return 0, ErrWrongType
+	}
+	e.value = n + delta
+	return n + delta, nil
+}
+
+// ttl returns the remaining seconds of key, -1 without expiry and -2 when missing.
+func (k *keyspace) ttl(key string) int64 {
+	e := k.get(key)
+	switch {
+	case e == nil:
+		return -2
+	case e.expiresAt.IsZero():
+		return -1
+	default:
+		return int64(math.Round(time.Until(e.expiresAt).Seconds()))
+	}
+}
+
+// pttl returns the remaining milliseconds of key, -1 without expiry and -2 when missing.
+func (k *keyspace) pttl(key string) int64 {
+	e := k.get(key)
+	switch {
+	case e == nil:
+		return -2
+	case e.expiresAt.IsZero():
+		return -1
+	default:
+		return time.Until(e.expiresAt).Milliseconds()
+	}
+}
+
+func (k *keyspace) expire(key string, ttl time.Duration) {
+	if e := k.get(key); e != nil {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+}
+
+func (k *keyspace) hash(key string) (map[string]float64, error) {
+	e := k.get(key)
+	if e == nil {
+		return nil, nil
+	}
+	h, ok := e.value.(map[string]float64)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return h, nil
+}
+
+// hset sets fields of the hash of key, keeping its expiry.
+func (k *keyspace) hset(key string, fields map[string]float64) error {
+	h, err := k.hash(key)
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		h = make(map[string]float64, len(fields))
+		k.entries[key] = &entry{value: h}
+	}
+	for field, value := range fields {
+		h[field] = value
+	}
+	return nil
+}
+
+// sortedSet returns the sorted set of key, creating an empty one when create is set.
+func (k *keyspace) sortedSet(key string, create bool) (*sortedSet, error) {
+	e := k.get(key)
+	if e == nil {
+		if !create {
+			return nil, nil
+		}
+		set := &sortedSet{}
+		k.entries[key] = &entry{value: set}
+		return set, nil
+	}
+	set, ok := e.value.(*sortedSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return set, nil
+}
+
+// deleteIfEmpty drops emptied sorted sets, as Redis does.
+func (k *keyspace) deleteIfEmpty(key string, set *sortedSet) {
+	if set != nil && len(set.members) == 0 {
+		delete(k.entries, key)
+	}
+}
+
+// sweep drops expired keys that were not accessed since they expired.
+func (k *keyspace) sweep(now time.Time) {
+	if now.Sub(k.lastSweep) < sweepInterval {
+		return
+	}
+	k.lastSweep = now
+
+	for key, e := range k.entries {
+		if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+			delete(k.entries, key)
+		}
+	}
+}
+
+// sortedSet keeps members ordered by score, then member.
+type sortedSet struct {
+	members []member
+}
+
+type member struct {
+	score float64
+	name  string
+}
+
+func (s *sortedSet) card() int {
+	return len(s.members)
+}
+
+// first returns the member with the lowest score.
+func (s *sortedSet) first() (member, bool) {
+	if len(s.members) == 0 {
+		return member{}, false
+	}
+	return s.members[0], true
+}
+
+func (s *sortedSet) add(score float64, name string) {
+	s.remove(name)
+	i := sort.Search(len(s.members), func(i int) bool {
+		m := s.members[i]
+		return m.score > score || (m.score == score && m.name >= name)
+	})
+	s.members = append(s.members, member{})
+	copy(s.members[i+1:], s.members[i:])
+	s.members[i] = member{score: score, name: name}
+}
+
+func (s *sortedSet) remove(name string) bool {
+	for i, m := range s.members {
+		if m.name == name {
+			s.members = append(s.members[:i], s.members[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// removeUpTo removes members scored at most maxScore.
+func (s *sortedSet) removeUpTo(maxScore float64) {
+	i := sort.Search(len(s.members), func(i int) bool {
+		return s.members[i].score > maxScore
+	})
+	s.members = s.members[i:]
+}
@@ -0,0 +1,239 @@
+package memory_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/memory"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// The parity tests run the same script calls through the Lua scripts, executed
+// by an in-process Redis, and through the native scripts of the memory store,
+// expecting the same replies.
+
+var algorithms = []string{
+	config.AlgorithmFixedWindow,
+	config.AlgorithmTokenBucket,
+	config.AlgorithmSlidingWindow,
+	config.AlgorithmLeakyBucket,
+	config.AlgorithmGCRA,
+	config.AlgorithmSlidingWindowCounter,
+	config.AlgorithmConcurrency,
+}
+
+// stores evaluates every script on both stores.
+type stores struct {
+	t      *testing.T
+	redis  *redis.Client
+	memory ports.LimiterScore
+	// sha1s are the SHA1s of the scripts by name, the same for both stores.
+	sha1s map[string]string
+}
+
+func newStores(t *testing.T) *stores {
+	t.Helper()
+
+	scripts := testutil.Scripts(t, algorithms...)
+	mem, err := memory.NewMemoryStore(testutil.NopLogger{}, scripts)
+	if err != nil {
+		t.Fatalf("memory store: %v", err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	s := &stores{t: t, redis: client, memory: mem, sha1s: make(map[string]string, len(scripts))}
+	ctx := context.Background()
+	for name, script := range scripts {
+		redisSHA1, err := client.ScriptLoad(ctx, script).Result()
+		if err != nil {
+			t.Fatalf("load script %s into redis: %v", name, err)
+		}
+		memorySHA1, err := mem.ScriptLoad(ctx, script)
+		if err != nil {
+			t.Fatalf("load script %s into memory store: %v", name, err)
+		}
+		if redisSHA1 != memorySHA1 {
+			t.Fatalf("script %s: redis SHA1 %s, memory store SHA1 %s", name, redisSHA1, memorySHA1)
+		}
+		s.sha1s[name] = redisSHA1
+	}
+	return s
+}
+
+// eval runs script on both stores, returning the Redis and memory store replies.
+func (s *stores) eval(script string, keys []string, args ...interface{}) (interface{}, interface{}) {
+	s.t.Helper()
+	ctx := context.Background()
+
+	want, err := s.redis.EvalSha(ctx, s.sha1s[script], keys, args...).Result()
+	if err != nil {
+		s.t.Fatalf("redis %s %v: %v", script, args, err)
+	}
+	got, err := s.memory.EvalSha(ctx, s.sha1s[script], keys, args)
+	if err != nil {
+		s.t.Fatalf("memory store %s %v: %v", script, args, err)
+	}
+	return want, got
+}
+
+// step is a call of an algorithm script at now milliseconds.
+type step struct {
+	now    int64
+	cost   int
+	dryRun bool
+}
+
+// call returns the keys and arguments of a single limit, the dry-run flag last.
+type call func(key string, s step) ([]string, []interface{})
+
+func dryRun(s step) string {
+	if s.dryRun {
+		return "1"
+	}
+	return "0"
+}
+
+var calls = map[string]call{
+	config.AlgorithmFixedWindow: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{60, 5, s.cost, dryRun(s)}
+	},
+	config.AlgorithmTokenBucket: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{5, 1, s.cost, s.now / 1000, 10, dryRun(s)}
+	},
+	config.AlgorithmSlidingWindow: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{10000, 5, s.now, "req-" + strconv.FormatInt(s.now, 10), s.cost, dryRun(s)}
+	},
+	config.AlgorithmLeakyBucket: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{5, 2, s.now, s.cost, dryRun(s)}
+	},
+	config.AlgorithmGCRA: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{500, 3, s.now, s.cost, dryRun(s)}
+	},
+	config.AlgorithmSlidingWindowCounter: func(key string, s step) ([]string, []interface{}) {
+		return []string{key + ":cur", key + ":prev"}, []interface{}{10000, 5, s.now, s.cost, dryRun(s)}
+	},
+	config.AlgorithmConcurrency: func(key string, s step) ([]string, []interface{}) {
+		return []string{key}, []interface{}{"acquire", 3, 30000, s.now, "lease-" + strconv.FormatInt(s.now, 10), s.cost, dryRun(s)}
+	},
+}
+
+// steps fill every limit, get rejected, check without consuming and recover
+// over time, some requests costing several units.
+var steps = []step{
+	{now: 1_000_000, cost: 1},
+	{now: 1_000_100, cost: 2},
+	{now: 1_000_200, cost: 1, dryRun: true},
+	{now: 1_000_300, cost: 1},
+	{now: 1_000_400, cost: 3},
+	{now: 1_000_500, cost: 1},
+	{now: 1_000_600, cost: 1, dryRun: true},
+	{now: 1_001_700, cost: 1},
+	{now: 1_003_000, cost: 2},
+	{now: 1_006_000, cost: 1},
+	{now: 1_011_000, cost: 4},
+	{now: 1_011_001, cost: 1},
+	{now: 1_025_000, cost: 1},
+}
+
+func TestAlgorithmParity(t *testing.T) {
+	for _, algo := range algorithms {
+		t.Run(algo, func(t *testing.T) {
+			s := newStores(t)
+			for i, st := range steps {
+				keys, args := calls[algo]("rl:"+algo+":{route:client}", st)
+				want, got := s.eval(algo, keys, args...)
+				if !reflect.DeepEqual(want, got) {
+					t.Fatalf("step %d %+v: lua replied %v, memory store %v", i, st, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiLimitParity(t *testing.T) {
+	stacked := []string{config.AlgorithmFixedWindow, config.AlgorithmGCRA, config.AlgorithmSlidingWindowCounter}
+	s := newStores(t)
+
+	for i, st := range steps {
+		header := []interface{}{len(stacked)}
+		var keys []string
+		var args []interface{}
+		for _, algo := range stacked {
+			limitKeys, limitArgs := calls[algo]("rl:"+algo+":{route:client}:"+algo, st)
+			header = append(header, algo, len(limitKeys), len(limitArgs))
+			keys = append(keys, limitKeys...)
+			args = append(args, limitArgs...)
+		}
+
+		want, got := s.eval(memory.MultiLimitScript, keys, append(header, args...)...)
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("step %d %+v: lua replied %v, memory store %v", i, st, want, got)
+		}
+	}
+}
+
+func TestConcurrencyReleaseParity(t *testing.T) {
+	s := newStores(t)
+	key := []string{"rl:concurrency:{route:client}"}
+	acquire := calls[config.AlgorithmConcurrency]
+
+	for _, st := range []step{{now: 1_000_000, cost: 2}, {now: 1_000_100, cost: 1}} {
+		keys, args := acquire(key[0], st)
+		if want, got := s.eval(config.AlgorithmConcurrency, keys, args...); !reflect.DeepEqual(want, got) {
+			t.Fatalf("acquire %+v: lua replied %v, memory store %v", st, want, got)
+		}
+	}
+
+	for _, release := range [][]interface{}{
+		{"release", "lease-1000000", 2},
+		{"release", "lease-1000000", 2},
+		{"release", "unknown", 1},
+	} {
+		if want, got := s.eval(config.AlgorithmConcurrency, key, release...); !reflect.DeepEqual(want, got) {
+			t.Fatalf("release %v: lua replied %v, memory store %v", release, want, got)
+		}
+	}
+
+	keys, args := acquire(key[0], step{now: 1_000_200, cost: 2})
+	if want, got := s.eval(config.AlgorithmConcurrency, keys, args...); !reflect.DeepEqual(want, got) {
+		t.Fatalf("acquire after release: lua replied %v, memory store %v", want, got)
+	}
+}
+
+func TestPenaltyParity(t *testing.T) {
+	s := newStores(t)
+	keys := []string{"pb:ban:{route:client}", "pb:rejections:{route:client}", "pb:strikes:{route:client}"}
+	reject := []interface{}{"reject", 3, 60000, 10000, 2, 0}
+
+	for i, args := range [][]interface{}{
+		{"check"}, reject, reject, {"check"}, reject, {"check"}, reject,
+	} {
+		want, got := s.eval(memory.PenaltyScript, keys, args...)
+		if !sameBan(want, got) {
+			t.Fatalf("call %d %v: lua replied %v, memory store %v", i, args, want, got)
+		}
+	}
+}
+
+// sameBan compares penalty replies, {banned, remaining ms, strike, newly
+// banned}, allowing for the time elapsed between the two calls in the
+// remaining milliseconds of a ban.
+func sameBan(want, got interface{}) bool {
+	w, ok := want.([]interface{})
+	if !ok {
+		return false
+	}
+	g, ok := got.([]interface{})
+	if !ok || len(w) != 4 || len(g) != 4 {
+		return false
+	}
+	remaining := w[1].(int64) - g[1].(int64)
+	return w[0] == g[0] && w[2] == g[2] && w[3] == g[3] && remaining >= 0 && remaining < 1000
+}
@@ -0,0 +1,579 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// nativeScript is the Go implementation of a Lua script in scripts/lua. It gets
+// the same KEYS and ARGV and returns what Redis would reply.
+type nativeScript func(data *keyspace, keys []string, args []string) (interface{}, error)
+
+var nativeScripts = map[string]nativeScript{
+	config.AlgorithmFixedWindow:          fixedWindow,
+	config.AlgorithmSlidingWindow:        slidingWindow,
+	config.AlgorithmTokenBucket:          tokenBucket,
+	config.AlgorithmLeakyBucket:          leakyBucket,
+	config.AlgorithmGCRA:                 gcra,
+	config.AlgorithmSlidingWindowCounter: slidingWindowCounter,
+	config.AlgorithmConcurrency:          concurrency,
+}
+
+// scriptArgs reads numeric ARGV like tonumber in Lua.
+type scriptArgs []string
+
+func (a scriptArgs) number(i int) (float64, error) {
+	if i >= len(a) {
+		return 0, fmt.Errorf("memory store: missing script argument %d", i+1)
+	}
+	n, err := strconv.ParseFloat(a[i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("memory store: script argument %d is not a number: %w", i+1, err)
+	}
+	return n, nil
+}
+
+func (a scriptArgs) numbers(n int) ([]float64, error) {
+	values := make([]float64, n)
+	for i := range values {
+		v, err := a.number(i)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func (a scriptArgs) dryRun(i int) bool {
+	return i < len(a) && a[i] == "1"
+}
+
+// reply converts Lua numbers to Redis integer replies, truncating like Redis does.
+func reply(values ...float64) []interface{} {
+	res := make([]interface{}, len(values))
+	for i, v := range values {
+		res[i] = int64(v)
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// fixedWindow mirrors scripts/lua/fixed_window.lua.
+func fixedWindow(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(3)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	window, limit, cost := n[0], n[1], n[2]
+
+	current, _ := data.number(key)
+	ttl := float64(data.ttl(key))
+
+	if current+cost > limit {
+		return reply(0, current, math.Max(limit-current, 0), ttl), nil
+	}
+
+	if args.dryRun(3) {
+		if ttl < 0 {
+			ttl = window
+		}
+		return reply(1, current+cost, limit-current-cost, ttl), nil
+	}
+
+	current, err = data.incrBy(key, cost)
+	if err != nil {
+		return nil, err
+	}
+	if ttl < 0 {
+		data.expire(key, seconds(window))
+		ttl = window
+	}
+
+	return reply(1, current, limit-current, ttl), nil
+}
+
+// slidingWindow mirrors scripts/lua/sliding_window.lua.
+func slidingWindow(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(3)
+	if err != nil {
+		return nil, err
+	}
+	cost, err := args.number(4)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	window, limit, now := n[0], n[1], n[2]
+	requestID := argv[3]
+
+	set, err := data.sortedSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var count float64
+	resetTime := 0.0
+	if set != nil {
+		// Remove old timestamps
+		set.removeUpTo(now - window)
+		data.deleteIfEmpty(key, set)
+		count = float64(set.card())
+		if oldest, ok := set.first(); ok {
+			resetTime = math.Ceil((oldest.score + window) / 1000)
+		}
+	}
+
+	if count+cost > limit {
+		return reply(0, count, math.Max(limit-count, 0), resetTime), nil
+	}
+
+	if count == 0 {
+		resetTime = math.Ceil((now + window) / 1000)
+	}
+
+	if args.dryRun(5) {
+		return reply(1, count+cost, limit-count-cost, resetTime), nil
+	}
+
+	set, err = data.sortedSet(key, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i <= int(cost); i++ {
+		set.add(now, requestID+":"+strconv.Itoa(i))
+	}
+	data.expire(key, seconds(math.Ceil(window/1000)))
+
+	return reply(1, count+cost, limit-count-cost, resetTime), nil
+}
+
+// tokenBucket mirrors scripts/lua/token_bucket.lua.
+func tokenBucket(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(5)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	capacity, refillRate, cost, now, bucketTTL := n[0], n[1], n[2], n[3], n[4]
+
+	bucket, err := data.hash(key)
+	if err != nil {
+		return nil, err
+	}
+	tokens, ok := bucket["tokens"]
+	if !ok {
+		tokens = capacity
+	}
+	lastRefill, ok := bucket["last_refill"]
+	if !ok {
+		lastRefill = now
+	}
+
+	// Refill tokens based on elapsed time
+	tokens = math.Min(capacity, tokens+(now-lastRefill)*refillRate)
+	lastRefill = now
+
+	timeUntilRefill := 0.0
+	if tokens < cost {
+		timeUntilRefill = math.Ceil((cost - tokens) / refillRate)
+	}
+	resetTime := now + timeUntilRefill
+
+	dryRun := args.dryRun(5)
+
+	if tokens < cost {
+		if !dryRun {
+			if err := data.hset(key, map[string]float64{"tokens": tokens, "last_refill": lastRefill}); err != nil {
+				return nil, err
+			}
+			data.expire(key, seconds(bucketTTL))
+		}
+		return reply(0, math.Floor(tokens), 0, resetTime), nil
+	}
+
+	if dryRun {
+		left := math.Floor(tokens - cost)
+		return reply(1, left, left, 0), nil
+	}
+
+	tokens -= cost
+	if err := data.hset(key, map[string]float64{"tokens": tokens, "last_refill": lastRefill}); err != nil {
+		return nil, err
+	}
+	data.expire(key, seconds(bucketTTL))
+
+	return reply(1, math.Floor(tokens), math.Floor(tokens), 0), nil
+}
+
+// leakyBucket mirrors scripts/lua/leaky_bucket.lua.
+func leakyBucket(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(4)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	capacity, leakRate, now, cost := n[0], n[1], n[2], n[3]
+
+	bucket, err := data.hash(key)
+	if err != nil {
+		return nil, err
+	}
+	level := bucket["level"]
+	lastLeak, ok := bucket["last_leak"]
+	if !ok {
+		lastLeak = now
+	}
+
+	// Drain the bucket at a constant rate since the last request
+	elapsed := math.Max(0, now-lastLeak)
+	level = math.Max(0, level-elapsed*leakRate/1000)
+
+	bucketTTL := math.Ceil(capacity/leakRate) + 1
+	dryRun := args.dryRun(4)
+
+	save := func() error {
+		if dryRun {
+			return nil
+		}
+		if err := data.hset(key, map[string]float64{"level": level, "last_leak": now}); err != nil {
+			return err
+		}
+		data.expire(key, seconds(bucketTTL))
+		return nil
+	}
+
+	if level+cost > capacity {
+		waitMs := math.Ceil((level + cost - capacity) * 1000 / leakRate)
+		if err := save(); err != nil {
+			return nil, err
+		}
+		return reply(0, math.Ceil(level), math.Max(math.Floor(capacity-level), 0), math.Ceil((now+waitMs)/1000), 0), nil
+	}
+
+	// Requests already queued must drain before this one leaves the bucket
+	delayMs := math.Floor(level * 1000 / leakRate)
+	level += cost
+	if err := save(); err != nil {
+		return nil, err
+	}
+
+	resetTime := math.Ceil((now + level*1000/leakRate) / 1000)
+	return reply(1, math.Ceil(level), math.Floor(capacity-level), resetTime, delayMs), nil
+}
+
+// gcra mirrors scripts/lua/gcra.lua.
+func gcra(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(4)
+	if err != nil {
+		return nil, err
+	}
+	key := keys[0]
+	emissionInterval, burst, now, cost := n[0], n[1], n[2], n[3]
+
+	delayTolerance := emissionInterval * burst
+
+	// A missing or past TAT means the client has its full burst available
+	tat, ok := data.number(key)
+	if !ok {
+		tat = now
+	}
+	tat = math.Max(tat, now)
+
+	newTAT := tat + emissionInterval*cost
+	allowAt := newTAT - delayTolerance
+
+	if now < allowAt {
+		retryAfter := math.Ceil(allowAt - now)
+		remaining := math.Max(math.Floor((now-(tat-delayTolerance))/emissionInterval), 0)
+		return reply(0, retryAfter, remaining, math.Ceil(allowAt/1000)), nil
+	}
+
+	if !args.dryRun(4) {
+		data.setNumber(key, newTAT, milliseconds(math.Ceil(newTAT-now)))
+	}
+
+	remaining := math.Floor((now - allowAt) / emissionInterval)
+	return reply(1, 0, remaining, math.Ceil(newTAT/1000)), nil
+}
+
+// slidingWindowCounter mirrors scripts/lua/sliding_window_counter.lua.
+func slidingWindowCounter(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	n, err := args.numbers(4)
+	if err != nil {
+		return nil, err
+	}
+	currentKey, previousKey := keys[0], keys[1]
+	window, limit, now, cost := n[0], n[1], n[2], n[3]
+
+	windowStart := now - math.Mod(now, window)
+	elapsed := (now - windowStart) / window
+
+	current, _ := data.number(currentKey)
+	previous, _ := data.number(previousKey)
+
+	// Weight the previous window by the part of it still inside the sliding window
+	estimated := previous*(1-elapsed) + current
+
+	if estimated+cost > limit {
+		resetAt := windowStart + window
+		if previous > 0 && current+cost <= limit {
+			overlap := (limit - cost - current) / previous
+			resetAt = windowStart + math.Ceil((1-overlap)*window)
+		}
+		return reply(0, math.Floor(estimated), math.Max(math.Floor(limit-estimated), 0), math.Ceil(resetAt/1000)), nil
+	}
+
+	if !args.dryRun(4) {
+		current, err = data.incrBy(currentKey, cost)
+		if err != nil {
+			return nil, err
+		}
+		if current == cost {
+			// The counter is still needed as the previous window of the next one
+			data.expire(currentKey, milliseconds(window*2))
+		}
+	}
+
+	estimated += cost
+	return reply(1, math.Ceil(estimated), math.Floor(limit-estimated), math.Ceil((windowStart+window)/1000)), nil
+}
+
+// concurrency mirrors scripts/lua/concurrency.lua.
+func concurrency(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	key := keys[0]
+
+	if len(argv) > 0 && argv[0] == "release" {
+		if len(argv) < 3 {
+			return nil, fmt.Errorf("memory store: missing release arguments")
+		}
+		cost, err := args.number(2)
+		if err != nil {
+			return nil, err
+		}
+		set, err := data.sortedSet(key, false)
+		if err != nil || set == nil {
+			return reply(0), err
+		}
+		removed := 0.0
+		for i := 1; i <= int(cost); i++ {
+			if set.remove(argv[1] + ":" + strconv.Itoa(i)) {
+				removed++
+			}
+		}
+		data.deleteIfEmpty(key, set)
+		return reply(removed), nil
+	}
+
+	limit, err := args.number(1)
+	if err != nil {
+		return nil, err
+	}
+	leaseTTL, err := args.number(2)
+	if err != nil {
+		return nil, err
+	}
+	now, err := args.number(3)
+	if err != nil {
+		return nil, err
+	}
+	cost, err := args.number(5)
+	if err != nil {
+		return nil, err
+	}
+	leaseID := argv[4]
+
+	set, err := data.sortedSet(key, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var inFlight float64
+	if set != nil {
+		// Drop leases that were never released
+		set.removeUpTo(now)
+		data.deleteIfEmpty(key, set)
+		inFlight = float64(set.card())
+	}
+
+	if inFlight+cost > limit {
+		// The earliest a slot is guaranteed to free up is when the oldest lease expires
+		resetTime := 0.0
+		if set != nil {
+			if oldest, ok := set.first(); ok {
+				resetTime = math.Ceil(oldest.score / 1000)
+			}
+		}
+		return reply(0, inFlight, math.Max(limit-inFlight, 0), resetTime), nil
+	}
+
+	if args.dryRun(6) {
+		return reply(1, inFlight+cost, limit-inFlight-cost, 0), nil
+	}
+
+	set, err = data.sortedSet(key, true)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i <= int(cost); i++ {
+		set.add(now+leaseTTL, leaseID+":"+strconv.Itoa(i))
+	}
+	data.expire(key, milliseconds(leaseTTL))
+
+	return reply(1, inFlight+cost, limit-inFlight-cost, 0), nil
+}
+
+// multiLimit mirrors scripts/lua/multi_limit.lua: every limit is checked with
+// its dry-run flag set, and consumed only when all of them pass, unless the
+// dry-run flag of a limit was set by the caller.
+func multiLimit(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	count, err := args.number(0)
+	if err != nil {
+		return nil, err
+	}
+
+	type call struct {
+		fn   nativeScript
+		keys []string
+		args []string
+	}
+
+	calls := make([]call, int(count))
+	dryRun := false
+	keyIndex := 0
+	argIndex := 1 + int(count)*3
+
+	for i := range calls {
+		base := 1 + i*3
+		if base+2 >= len(argv) {
+			return nil, fmt.Errorf("memory store: missing stacked limit header")
+		}
+		fn, ok := nativeScripts[argv[base]]
+		if !ok {
+			return nil, fmt.Errorf("unknown algorithm %s", argv[base])
+		}
+		keyCount, err := args.number(base + 1)
+		if err != nil {
+			return nil, err
+		}
+		argCount, err := args.number(base + 2)
+		if err != nil {
+			return nil, err
+		}
+		if keyIndex+int(keyCount) > len(keys) || argIndex+int(argCount) > len(argv) {
+			return nil, fmt.Errorf("memory store: stacked limit %d is out of range", i+1)
+		}
+
+		calls[i] = call{
+			fn:   fn,
+			keys: keys[keyIndex : keyIndex+int(keyCount)],
+			args: append([]string(nil), argv[argIndex:argIndex+int(argCount)]...),
+		}
+		if argCount > 0 && args.dryRun(argIndex+int(argCount)-1) {
+			dryRun = true
+		}
+		keyIndex += int(keyCount)
+		argIndex += int(argCount)
+	}
+
+	results := make([]interface{}, len(calls))
+	allowed := true
+	for i, c := range calls {
+		c.args[len(c.args)-1] = "1"
+		res, err := c.fn(data, c.keys, c.args)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = res
+		if values, ok := res.([]interface{}); !ok || len(values) == 0 || values[0] == int64(0) {
+			allowed = false
+		}
+	}
+
+	if !allowed || dryRun {
+		return results, nil
+	}
+
+	for i, c := range calls {
+		c.args[len(c.args)-1] = "0"
+		res, err := c.fn(data, c.keys, c.args)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = res
+	}
+	return results, nil
+}
+
+// penalty mirrors scripts/lua/penalty.lua.
+func penalty(data *keyspace, keys []string, argv []string) (interface{}, error) {
+	args := scriptArgs(argv)
+	banKey, rejectionsKey, strikesKey := keys[0], keys[1], keys[2]
+
+	if remaining := data.pttl(banKey); remaining > 0 {
+		strike, _ := data.number(banKey)
+		return reply(1, float64(remaining), strike, 0), nil
+	}
+
+	if len(argv) > 0 && argv[0] == "check" {
+		return reply(0, 0, 0, 0), nil
+	}
+
+	n := make([]float64, 5)
+	for i := range n {
+		v, err := args.number(i + 1)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = v
+	}
+	threshold, window, duration, multiplier, maxDuration := n[0], n[1], n[2], n[3], n[4]
+
+	rejections, err := data.incrBy(rejectionsKey, 1)
+	if err != nil {
+		return nil, err
+	}
+	if rejections == 1 {
+		data.expire(rejectionsKey, milliseconds(window))
+	}
+
+	if rejections < threshold {
+		return reply(0, 0, 0, 0), nil
+	}
+
+	delete(data.entries, rejectionsKey)
+	strike, err := data.incrBy(strikesKey, 1)
+	if err != nil {
+		return nil, err
+	}
+	ban := duration * math.Pow(multiplier, strike-1)
+	if maxDuration > 0 && ban > maxDuration {
+		ban = maxDuration
+	}
+	ban = math.Floor(ban)
+
+	data.setNumber(banKey, strike, milliseconds(ban))
+	data.expire(strikesKey, milliseconds(ban+math.Max(maxDuration, duration)))
+
+	return reply(1, ban, strike, 1), nil
+}
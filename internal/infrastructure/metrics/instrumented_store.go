@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// InstrumentedStore is a ports.LimiterScore decorator recording the latency of
+// EvalSha, which every limiter check goes through.
+type InstrumentedStore struct {
+	ports.LimiterScore
+	metrics ports.Metrics
+}
+
+func NewInstrumentedStore(store ports.LimiterScore, metrics ports.Metrics) ports.LimiterScore {
+	return &InstrumentedStore{
+		LimiterScore: store,
+		metrics:      metrics,
+	}
+}
+
+func (s *InstrumentedStore) EvalSha(ctx context.Context, sha1 string, keys []string, args ...[]interface{}) (interface{}, error) {
+	start := time.Now()
+	result, err := s.LimiterScore.EvalSha(ctx, sha1, keys, args...)
+	s.metrics.ObserveStoreLatency("evalsha", time.Since(start), err)
+	return result, err
+}
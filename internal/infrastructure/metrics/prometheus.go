@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/resilience"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rate_limiter"
+
+// PrometheusMetrics is a ports.Metrics exposing its metrics in the Prometheus
+// text format, on its own registry rather than the global one.
+type PrometheusMetrics struct {
+	registry        *prometheus.Registry
+	decisions       *prometheus.CounterVec
+	whitelistBypass *prometheus.CounterVec
+	denylistBlocks  *prometheus.CounterVec
+	failureModes    *prometheus.CounterVec
+	configReloads   *prometheus.CounterVec
+	storeLatency    *prometheus.HistogramVec
+	upstreamLatency *prometheus.HistogramVec
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	m := &PrometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decisions_total",
+			Help:      "Requests allowed or denied, by route and algorithm.",
+		}, []string{"route", "algorithm", "decision"}),
+		whitelistBypass: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "whitelist_bypass_total",
+			Help:      "Requests from whitelisted IPs that bypassed rate limiting, by route.",
+		}, []string{"route"}),
+		denylistBlocks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "denylist_blocks_total",
+			Help:      "Requests from denied IPs that were refused, by route.",
+		}, []string{"route"}),
+		failureModes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failure_mode_total",
+			Help:      "Requests decided by a failure mode while the limiter store was unavailable, by route and mode.",
+		}, []string{"route", "mode"}),
+		configReloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reloads_total",
+			Help:      "Configuration reloads from the provider, by result.",
+		}, []string{"result"}),
+		storeLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "redis_duration_seconds",
+			Help:      "Duration of Redis operations, by operation and result.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "result"}),
+		upstreamLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_duration_seconds",
+			Help:      "Duration of requests proxied to the backend, by route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "code"}),
+	}
+
+	m.registry.MustRegister(
+		m.decisions,
+		m.whitelistBypass,
+		m.denylistBlocks,
+		m.failureModes,
+		m.configReloads,
+		m.storeLatency,
+		m.upstreamLatency,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// RegisterCircuitBreaker exposes the state of the Redis circuit breaker as a
+// gauge: 0 closed, 1 open, 2 half open.
+func (m *PrometheusMetrics) RegisterCircuitBreaker(cb *resilience.CircuitBreaker) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "State of the Redis circuit breaker: 0 closed, 1 open, 2 half open.",
+	}, func() float64 {
+		return float64(cb.GetState())
+	}))
+}
+
+// Handler serves the registered metrics.
+func (m *PrometheusMetrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+func (m *PrometheusMetrics) RecordDecision(route, algorithm string, allowed bool) {
+	decision := "denied"
+	if allowed {
+		decision = "allowed"
+	}
+	m.decisions.WithLabelValues(route, algorithm, decision).Inc()
+}
+
+func (m *PrometheusMetrics) RecordWhitelistBypass(route string) {
+	m.whitelistBypass.WithLabelValues(route).Inc()
+}
+
+func (m *PrometheusMetrics) RecordDenylistBlock(route string) {
+	m.denylistBlocks.WithLabelValues(route).Inc()
+}
+
+func (m *PrometheusMetrics) RecordFailureMode(route, mode string) {
+	m.failureModes.WithLabelValues(route, mode).Inc()
+}
+
+func (m *PrometheusMetrics) RecordConfigReload(success bool) {
+	m.configReloads.WithLabelValues(result(success)).Inc()
+}
+
+func (m *PrometheusMetrics) ObserveStoreLatency(operation string, duration time.Duration, err error) {
+	m.storeLatency.WithLabelValues(operation, result(err == nil)).Observe(duration.Seconds())
+}
+
+func (m *PrometheusMetrics) ObserveUpstreamLatency(route string, status int, duration time.Duration) {
+	m.upstreamLatency.WithLabelValues(route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+func result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
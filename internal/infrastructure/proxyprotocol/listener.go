@@ -0,0 +1,42 @@
+package proxyprotocol
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+
+	domainConfig "github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/pires/go-proxyproto"
+)
+
+// ErrNoTrustedProxies is returned by NewListener without trusted proxies, as
+// any peer could then claim to connect on behalf of any client.
+var ErrNoTrustedProxies = errors.New("proxy protocol requires trusted proxies")
+
+// NewListener wraps ln to read the PROXY protocol header, v1 or v2, sent by load
+// balancers ahead of the connection, so that RemoteAddr is the address of the
+// client rather than of the load balancer. Connections from trustedProxies must
+// send the header; connections from other peers are served as is, and closed
+// when they send one.
+func NewListener(ln net.Listener, trustedProxies domainConfig.IPRanges) (net.Listener, error) {
+	if len(trustedProxies) == 0 {
+		return nil, ErrNoTrustedProxies
+	}
+	return &proxyproto.Listener{
+		Listener: ln,
+		ConnPolicy: func(opts proxyproto.ConnPolicyOptions) (proxyproto.Policy, error) {
+			if trusted(opts.Upstream, trustedProxies) {
+				return proxyproto.REQUIRE, nil
+			}
+			return proxyproto.REJECT, nil
+		},
+	}, nil
+}
+
+func trusted(upstream net.Addr, trustedProxies domainConfig.IPRanges) bool {
+	addrPort, err := netip.ParseAddrPort(upstream.String())
+	if err != nil {
+		return false
+	}
+	return trustedProxies.ContainsAddr(addrPort.Addr())
+}
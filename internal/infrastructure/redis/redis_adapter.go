@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,6 +10,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// scanCount is the number of keys scanned or deleted per round trip.
+const scanCount = 1000
+
 type RedisAdapter struct {
 	client           *redis.Client
 	logger           ports.Logger
@@ -101,3 +105,77 @@ func (r *RedisAdapter) EvalSha(ctx context.Context, sha1 string, keys []string,
 	}
 	return res, nil
 }
+
+func (r *RedisAdapter) Inspect(ctx context.Context, key string) (ports.KeyState, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.operationTimeout)
+	defer cancel()
+
+	pipe := r.client.Pipeline()
+	typeCmd := pipe.Type(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		r.logger.Error(fmt.Sprintf("RedisAdapter:Inspect error for key: %s", key), ports.Field{Key: "error", Val: err})
+		return ports.KeyState{}, err
+	}
+
+	state := ports.KeyState{Key: key, Type: typeCmd.Val(), TTL: ttlCmd.Val()}
+	if state.TTL < 0 {
+		state.TTL = -1
+	}
+
+	var err error
+	switch state.Type {
+	case ports.KeyTypeString:
+		state.Value, err = r.client.Get(ctx, key).Result()
+	case ports.KeyTypeHash:
+		state.Fields, err = r.client.HGetAll(ctx, key).Result()
+	case ports.KeyTypeSortedSet:
+		var members []redis.Z
+		members, err = r.client.ZRangeWithScores(ctx, key, 0, -1).Result()
+		for _, m := range members {
+			state.Members = append(state.Members, ports.ScoredMember{Member: fmt.Sprint(m.Member), Score: m.Score})
+		}
+	}
+	if errors.Is(err, redis.Nil) {
+		// The key expired between the two round trips
+		return ports.KeyState{Key: key, Type: ports.KeyTypeNone, TTL: -1}, nil
+	}
+	if err != nil {
+		r.logger.Error(fmt.Sprintf("RedisAdapter:Inspect error for key: %s", key), ports.Field{Key: "error", Val: err})
+		return ports.KeyState{}, err
+	}
+	return state, nil
+}
+
+func (r *RedisAdapter) Keys(ctx context.Context, pattern string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.operationTimeout)
+	defer cancel()
+
+	keys := make([]string, 0)
+	iter := r.client.Scan(ctx, 0, pattern, scanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		r.logger.Error("RedisAdapter:Keys error", ports.Field{Key: "error", Val: err}, ports.Field{Key: "pattern", Val: pattern})
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *RedisAdapter) Delete(ctx context.Context, keys ...string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.operationTimeout)
+	defer cancel()
+
+	var deleted int64
+	for start := 0; start < len(keys); start += scanCount {
+		end := min(start+scanCount, len(keys))
+		n, err := r.client.Unlink(ctx, keys[start:end]...).Result()
+		if err != nil {
+			r.logger.Error("RedisAdapter:Delete error", ports.Field{Key: "error", Val: err})
+			return deleted, err
+		}
+		deleted += n
+	}
+	return deleted, nil
+}
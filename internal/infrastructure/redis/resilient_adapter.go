@@ -2,10 +2,12 @@ package redis
 
 import (
 	"context"
-	"time"
+	"errors"
 
 	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	domainErrors "github.com/SilentPlaces/rate_limiter/internal/domain/errors"
 	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/resilience"
+	"github.com/redis/go-redis/v9"
 )
 
 type ResilientRedisAdapter struct {
@@ -14,16 +16,16 @@ type ResilientRedisAdapter struct {
 	logger         ports.Logger
 }
 
-func NewResilientRedisAdapter(adapter ports.LimiterScore, logger ports.Logger, maxFailures int, timeout time.Duration) ports.LimiterScore {
+func NewResilientRedisAdapter(adapter ports.LimiterScore, logger ports.Logger, circuitBreaker *resilience.CircuitBreaker) ports.LimiterScore {
 	return &ResilientRedisAdapter{
 		adapter:        adapter,
-		circuitBreaker: resilience.NewCircuitBreaker(maxFailures, timeout),
+		circuitBreaker: circuitBreaker,
 		logger:         logger,
 	}
 }
 
 func (r *ResilientRedisAdapter) Get(ctx context.Context, key string) interface{} {
-	result, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		val := r.adapter.Get(ctx, key)
 		if err, ok := val.(error); ok {
 			return nil, err
@@ -39,21 +41,21 @@ func (r *ResilientRedisAdapter) Get(ctx context.Context, key string) interface{}
 }
 
 func (r *ResilientRedisAdapter) Set(ctx context.Context, key string, value interface{}, ttlSeconds int) error {
-	_, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	_, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		return nil, r.adapter.Set(ctx, key, value, ttlSeconds)
 	})
 	return err
 }
 
 func (r *ResilientRedisAdapter) Incr(ctx context.Context, key string) error {
-	_, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	_, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		return nil, r.adapter.Incr(ctx, key)
 	})
 	return err
 }
 
 func (r *ResilientRedisAdapter) Eval(ctx context.Context, script string, keys []string, args ...[]interface{}) (interface{}, error) {
-	result, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		return r.adapter.Eval(ctx, script, keys, args...)
 	})
 
@@ -64,7 +66,7 @@ func (r *ResilientRedisAdapter) Eval(ctx context.Context, script string, keys []
 }
 
 func (r *ResilientRedisAdapter) ScriptLoad(ctx context.Context, script string) (string, error) {
-	result, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		return r.adapter.ScriptLoad(ctx, script)
 	})
 
@@ -75,7 +77,7 @@ func (r *ResilientRedisAdapter) ScriptLoad(ctx context.Context, script string) (
 }
 
 func (r *ResilientRedisAdapter) EvalSha(ctx context.Context, sha1 string, keys []string, args ...[]interface{}) (interface{}, error) {
-	result, err := r.circuitBreaker.Execute(ctx, func(ctx context.Context) (interface{}, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
 		return r.adapter.EvalSha(ctx, sha1, keys, args...)
 	})
 
@@ -84,3 +86,49 @@ func (r *ResilientRedisAdapter) EvalSha(ctx context.Context, sha1 string, keys [
 	}
 	return result, nil
 }
+
+func (r *ResilientRedisAdapter) Inspect(ctx context.Context, key string) (ports.KeyState, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
+		return r.adapter.Inspect(ctx, key)
+	})
+
+	if err != nil {
+		return ports.KeyState{}, err
+	}
+	return result.(ports.KeyState), nil
+}
+
+func (r *ResilientRedisAdapter) Keys(ctx context.Context, pattern string) ([]string, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
+		return r.adapter.Keys(ctx, pattern)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result.([]string), nil
+}
+
+func (r *ResilientRedisAdapter) Delete(ctx context.Context, keys ...string) (int64, error) {
+	result, err := r.execute(ctx, func(ctx context.Context) (interface{}, error) {
+		return r.adapter.Delete(ctx, keys...)
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	return result.(int64), nil
+}
+
+// execute runs fn through the circuit breaker. Failures to reach Redis, including
+// an open circuit breaker, are reported as ErrStoreUnavailable; errors Redis
+// replied with, e.g. NOSCRIPT or a Lua error, are returned as they are, as no
+// failure mode should hide them.
+func (r *ResilientRedisAdapter) execute(ctx context.Context, fn func(context.Context) (interface{}, error)) (interface{}, error) {
+	result, err := r.circuitBreaker.Execute(ctx, fn)
+	var reply redis.Error
+	if err == nil || errors.As(err, &reply) {
+		return result, err
+	}
+	return result, domainErrors.NewRateLimiterError(domainErrors.ErrStoreUnavailable.Code, domainErrors.ErrStoreUnavailable.Message, err)
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
 )
 
 type State int
@@ -15,13 +17,30 @@ const (
 	StateHalfOpen
 )
 
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
 type CircuitBreaker struct {
 	maxFailures  int
 	timeout      time.Duration
 	state        State
 	failures     int
 	lastFailTime time.Time
-	mu           sync.RWMutex
+	// override forces the state while overridden is set, e.g. to stop sending
+	// traffic to Redis during maintenance.
+	override   State
+	overridden bool
+	mu         sync.RWMutex
 }
 
 func NewCircuitBreaker(maxFailures int, timeout time.Duration) *CircuitBreaker {
@@ -47,6 +66,13 @@ func (cb *CircuitBreaker) beforeRequest() error {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if cb.overridden {
+		if cb.override == StateOpen {
+			return fmt.Errorf("circuit breaker is forced open")
+		}
+		return nil
+	}
+
 	switch cb.state {
 	case StateOpen:
 		if time.Since(cb.lastFailTime) > cb.timeout {
@@ -65,6 +91,10 @@ func (cb *CircuitBreaker) afterRequest(err error) {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	if cb.overridden {
+		return
+	}
+
 	if err != nil {
 		cb.failures++
 		cb.lastFailTime = time.Now()
@@ -84,5 +114,44 @@ func (cb *CircuitBreaker) afterRequest(err error) {
 func (cb *CircuitBreaker) GetState() State {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
+	if cb.overridden {
+		return cb.override
+	}
 	return cb.state
 }
+
+func (cb *CircuitBreaker) Status() ports.CircuitBreakerStatus {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	status := ports.CircuitBreakerStatus{
+		State:    cb.state.String(),
+		Failures: cb.failures,
+	}
+	if cb.overridden {
+		status.State = cb.override.String()
+		status.Override = cb.override.String()
+	}
+	return status
+}
+
+// SetOverride forces the breaker open or closed, or resumes automatic state
+// changes from a closed breaker when state is empty.
+func (cb *CircuitBreaker) SetOverride(state string) error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch state {
+	case StateOpen.String():
+		cb.override, cb.overridden = StateOpen, true
+	case StateClosed.String():
+		cb.override, cb.overridden = StateClosed, true
+	case "":
+		cb.overridden = false
+		cb.state = StateClosed
+		cb.failures = 0
+	default:
+		return fmt.Errorf("circuit breaker override must be %q, %q or empty, got %q", StateOpen, StateClosed, state)
+	}
+	return nil
+}
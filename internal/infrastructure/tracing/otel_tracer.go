@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	appConfig "github.com/SilentPlaces/rate_limiter/config"
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	tracerName         = "github.com/SilentPlaces/rate_limiter"
+	defaultServiceName = "rate_limiter"
+)
+
+// OtelTracer is a ports.Tracer backed by OpenTelemetry, propagating the trace
+// context with W3C traceparent and baggage headers.
+type OtelTracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	shutdown   func(context.Context) error
+}
+
+// NewOtelTracer creates a tracer exporting spans with the configured exporter:
+// appConfig.TracingExporterOTLP over OTLP/HTTP, appConfig.TracingExporterStdout
+// to standard output for local testing, or nowhere by default.
+func NewOtelTracer(ctx context.Context, cfg appConfig.TracingConfig) (*OtelTracer, error) {
+	t := &OtelTracer{
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+		shutdown:   func(context.Context) error { return nil },
+	}
+
+	// An unset ratio samples every trace, a zero ratio none
+	sampleRatio := 1.0
+	if cfg.SampleRatio != nil {
+		sampleRatio = *cfg.SampleRatio
+	}
+	if sampleRatio < 0 || sampleRatio > 1 {
+		return nil, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %v", sampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case "", appConfig.TracingExporterNone:
+		t.tracer = noop.NewTracerProvider().Tracer(tracerName)
+		return t, nil
+	case appConfig.TracingExporterStdout:
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("stdout exporter: %w", err)
+		}
+		exporter = stdoutExporter
+	case appConfig.TracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		otlpExporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("otlp exporter: %w", err)
+		}
+		exporter = otlpExporter
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	)
+	t.tracer = provider.Tracer(tracerName)
+	t.shutdown = provider.Shutdown
+	return t, nil
+}
+
+func (t *OtelTracer) Start(ctx context.Context, name string, kind ports.SpanKind, attributes ...ports.Field) (context.Context, ports.Span) {
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(spanKind(kind)),
+		trace.WithAttributes(toAttributes(attributes)...),
+	)
+	return ctx, otelSpan{span: span}
+}
+
+func (t *OtelTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+func (t *OtelTracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Shutdown flushes the spans not exported yet.
+func (t *OtelTracer) Shutdown(ctx context.Context) error {
+	return t.shutdown(ctx)
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attributes ...ports.Field) {
+	s.span.SetAttributes(toAttributes(attributes)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+func spanKind(kind ports.SpanKind) trace.SpanKind {
+	switch kind {
+	case ports.SpanKindServer:
+		return trace.SpanKindServer
+	case ports.SpanKindClient:
+		return trace.SpanKindClient
+	default:
+		return trace.SpanKindInternal
+	}
+}
+
+func toAttributes(fields []ports.Field) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(fields))
+	for _, field := range fields {
+		switch v := field.Val.(type) {
+		case string:
+			attributes = append(attributes, attribute.String(field.Key, v))
+		case bool:
+			attributes = append(attributes, attribute.Bool(field.Key, v))
+		case int:
+			attributes = append(attributes, attribute.Int(field.Key, v))
+		case int64:
+			attributes = append(attributes, attribute.Int64(field.Key, v))
+		case float64:
+			attributes = append(attributes, attribute.Float64(field.Key, v))
+		case []string:
+			attributes = append(attributes, attribute.StringSlice(field.Key, v))
+		case time.Duration:
+			attributes = append(attributes, attribute.Float64(field.Key, v.Seconds()))
+		default:
+			attributes = append(attributes, attribute.String(field.Key, fmt.Sprint(v)))
+		}
+	}
+	return attributes
+}
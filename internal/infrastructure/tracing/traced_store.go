@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+
+	appConfig "github.com/SilentPlaces/rate_limiter/config"
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// TracedStore is a ports.LimiterScore decorator recording a client span for
+// every EvalSha call, named after the store type: "redis.EvalSha" or
+// "memory.EvalSha". Only Redis spans carry db.system, the memory store being
+// no database.
+type TracedStore struct {
+	ports.LimiterScore
+	tracer    ports.Tracer
+	storeType string
+}
+
+func NewTracedStore(store ports.LimiterScore, tracer ports.Tracer, storeType string) ports.LimiterScore {
+	return &TracedStore{
+		LimiterScore: store,
+		tracer:       tracer,
+		storeType:    storeType,
+	}
+}
+
+func (s *TracedStore) EvalSha(ctx context.Context, sha1 string, keys []string, args ...[]interface{}) (interface{}, error) {
+	fields := []ports.Field{
+		{Key: "db.operation.name", Val: "EVALSHA"},
+		{Key: "rate_limiter.script_sha1", Val: sha1},
+		{Key: "rate_limiter.key_count", Val: len(keys)},
+	}
+	if s.storeType == appConfig.StoreRedis {
+		fields = append(fields, ports.Field{Key: "db.system", Val: "redis"})
+	}
+	ctx, span := s.tracer.Start(ctx, s.storeType+".EvalSha", ports.SpanKindClient, fields...)
+	defer span.End()
+
+	result, err := s.LimiterScore.EvalSha(ctx, sha1, keys, args...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
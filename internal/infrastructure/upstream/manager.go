@@ -0,0 +1,181 @@
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// Manager keeps the upstream pool of every route, each with its own health
+// checks. Pools are built on first use and rebuilt when the upstream of their
+// route changes; Watch stops the pools of routes removed from the config.
+type Manager struct {
+	logger ports.Logger
+	client *http.Client
+	ctx    context.Context
+	mu     sync.RWMutex
+	pools  map[string]*pool
+	// onRemove is called with the targets left in no pool once pools are dropped.
+	onRemove func(targets []*url.URL)
+}
+
+// NewManager creates a manager whose health checks run until ctx is done.
+func NewManager(ctx context.Context, log ports.Logger) *Manager {
+	return &Manager{
+		logger: log,
+		client: &http.Client{
+			// A redirecting health check endpoint answers for itself
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		ctx:   ctx,
+		pools: make(map[string]*pool),
+	}
+}
+
+// OnRemove registers fn to be called with the targets of dropped pools that no
+// other pool balances over, e.g. to release what was kept for them.
+func (m *Manager) OnRemove(fn func(targets []*url.URL)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRemove = fn
+}
+
+func (m *Manager) Pick(route string, upstream config.UpstreamConfig) (*url.URL, func(), bool) {
+	if !upstream.Enabled() {
+		return nil, nil, false
+	}
+
+	id := poolID(upstream)
+	m.mu.RLock()
+	p, ok := m.pools[route]
+	m.mu.RUnlock()
+	if !ok || p.id != id {
+		p = m.replace(route, upstream, id)
+	}
+
+	t, ok := p.pick()
+	if !ok {
+		return nil, nil, false
+	}
+	t.active.Add(1)
+	return t.url, func() { t.active.Add(-1) }, true
+}
+
+// poolID returns the ID of upstream, fingerprinting configs that were not parsed.
+func poolID(upstream config.UpstreamConfig) string {
+	if upstream.ID != "" {
+		return upstream.ID
+	}
+	return upstream.Fingerprint()
+}
+
+// replace swaps the pool of route for one built from upstream, unless another
+// request already did.
+func (m *Manager) replace(route string, upstream config.UpstreamConfig, id string) *pool {
+	m.mu.Lock()
+	var dropped *pool
+	if p, ok := m.pools[route]; ok {
+		if p.id == id {
+			m.mu.Unlock()
+			return p
+		}
+		p.stop()
+		dropped = p
+	}
+
+	p := newPool(route, id, upstream)
+	if upstream.HealthCheck.Enabled() {
+		ctx, cancel := context.WithCancel(m.ctx)
+		p.cancel = cancel
+		go p.checkHealth(ctx, m.client, m.logger)
+	}
+	m.pools[route] = p
+	removed, onRemove := m.orphans(dropped), m.onRemove
+	m.mu.Unlock()
+
+	m.logger.Info("upstream pool built",
+		ports.Field{Key: "route", Val: route},
+		ports.Field{Key: "targets", Val: len(p.targets)},
+		ports.Field{Key: "balancer", Val: upstream.BalancerName()},
+	)
+	if len(removed) > 0 && onRemove != nil {
+		onRemove(removed)
+	}
+	return p
+}
+
+// orphans returns the targets of the dropped pools that no pool balances over
+// anymore. It must be called with mu held.
+func (m *Manager) orphans(dropped ...*pool) []*url.URL {
+	kept := make(map[string]bool)
+	for _, p := range m.pools {
+		for _, t := range p.targets {
+			kept[t.url.String()] = true
+		}
+	}
+
+	var orphans []*url.URL
+	for _, p := range dropped {
+		if p == nil {
+			continue
+		}
+		for _, t := range p.targets {
+			if !kept[t.url.String()] {
+				kept[t.url.String()] = true
+				orphans = append(orphans, t.url)
+			}
+		}
+	}
+	return orphans
+}
+
+// Watch stops the pools of routes without an upstream in the config of
+// configService, checked every interval until ctx is done.
+func (m *Manager) Watch(ctx context.Context, configService ports.ConfigService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.prune(configService.GetConfig())
+		}
+	}
+}
+
+func (m *Manager) prune(cfg config.Config) {
+	m.mu.Lock()
+	var dropped []*pool
+	for route, p := range m.pools {
+		if routeConfig, ok := upstreamRoute(cfg, route); ok && routeConfig.Upstream.Enabled() {
+			continue
+		}
+		p.stop()
+		delete(m.pools, route)
+		dropped = append(dropped, p)
+		m.logger.Info("upstream pool removed", ports.Field{Key: "route", Val: route})
+	}
+	removed, onRemove := m.orphans(dropped...), m.onRemove
+	m.mu.Unlock()
+
+	if len(removed) > 0 && onRemove != nil {
+		onRemove(removed)
+	}
+}
+
+// upstreamRoute returns the config of route as pools are named: by route, or
+// config.DefaultRoute for the default route.
+func upstreamRoute(cfg config.Config, route string) (config.RouteConfig, bool) {
+	if route == config.DefaultRoute && cfg.Default != nil {
+		return *cfg.Default, true
+	}
+	routeConfig, ok := cfg.Routes[route]
+	return routeConfig, ok
+}
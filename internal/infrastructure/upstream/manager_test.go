@@ -0,0 +1,129 @@
+package upstream
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+)
+
+// removals records the targets reported by Manager.OnRemove.
+type removals struct {
+	targets []string
+}
+
+func (r *removals) record(targets []*url.URL) {
+	for _, target := range targets {
+		r.targets = append(r.targets, target.String())
+	}
+}
+
+func (r *removals) take() []string {
+	targets := r.targets
+	r.targets = nil
+	slices.Sort(targets)
+	return targets
+}
+
+func newTestManager(t *testing.T) (*Manager, *removals) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	m := NewManager(ctx, testutil.NopLogger{})
+	r := &removals{}
+	m.OnRemove(r.record)
+	return m, r
+}
+
+// upstream returns the config of a pool balancing over backends, as parsed.
+func upstream(backends ...*backend) config.UpstreamConfig {
+	u := config.UpstreamConfig{Targets: targets(backends...)}
+	u.ID = u.Fingerprint()
+	return u
+}
+
+func TestManagerKeepsPoolOfUnchangedUpstream(t *testing.T) {
+	m, removed := newTestManager(t)
+	a := newBackend(t, "a")
+
+	target, done, ok := m.Pick("api", upstream(a))
+	if !ok {
+		t.Fatal("no target picked")
+	}
+	done()
+	if got := get(t, target.String()); got != "a" {
+		t.Errorf("served by %q, want a", got)
+	}
+	p := m.pools["api"]
+
+	// A reload parses an equal config again
+	if _, done, _ := m.Pick("api", upstream(a)); done != nil {
+		done()
+	}
+	if m.pools["api"] != p {
+		t.Error("pool rebuilt for an unchanged upstream")
+	}
+	if got := removed.take(); len(got) != 0 {
+		t.Errorf("targets %v removed, want none", got)
+	}
+}
+
+func TestManagerRebuildsPoolOfChangedUpstream(t *testing.T) {
+	m, removed := newTestManager(t)
+	a, b, c := newBackend(t, "a"), newBackend(t, "b"), newBackend(t, "c")
+
+	_, done, _ := m.Pick("api", upstream(a, b))
+	done()
+	_, done, _ = m.Pick("other", upstream(b))
+	done()
+
+	target, done, ok := m.Pick("api", upstream(c))
+	if !ok {
+		t.Fatal("no target picked")
+	}
+	done()
+	if got := get(t, target.String()); got != "c" {
+		t.Errorf("served by %q after the reload, want c", got)
+	}
+	// b is still balanced over by the other route
+	if got, want := removed.take(), []string{a.URL}; !slices.Equal(got, want) {
+		t.Errorf("targets %v removed, want %v", got, want)
+	}
+}
+
+func TestManagerPrunesRemovedRoutes(t *testing.T) {
+	m, removed := newTestManager(t)
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+
+	for route, u := range map[string]config.UpstreamConfig{"api": upstream(a), "other": upstream(b), config.DefaultRoute: upstream(b)} {
+		_, done, _ := m.Pick(route, u)
+		done()
+	}
+
+	// The api route is gone and the other route no longer has an upstream
+	m.prune(config.Config{
+		Routes:  map[string]config.RouteConfig{"other": {}},
+		Default: &config.RouteConfig{Upstream: upstream(b)},
+	})
+	if _, ok := m.pools["api"]; ok {
+		t.Error("pool of a removed route kept")
+	}
+	if _, ok := m.pools["other"]; ok {
+		t.Error("pool of a route without upstream kept")
+	}
+	if _, ok := m.pools[config.DefaultRoute]; !ok {
+		t.Error("pool of the default route removed")
+	}
+	if got, want := removed.take(), []string{a.URL}; !slices.Equal(got, want) {
+		t.Errorf("targets %v removed, want %v", got, want)
+	}
+
+	m.prune(config.Config{})
+	if got, want := removed.take(), []string{b.URL}; !slices.Equal(got, want) {
+		t.Errorf("targets %v removed, want %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package upstream
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// pool balances the requests of a route over its targets. Targets are healthy
+// until health checks fail; when none is healthy, requests are spread over all
+// of them rather than failed, as the checks may be wrong.
+type pool struct {
+	route string
+	// id is the ID of config, telling whether the pool is still current
+	id      string
+	config  config.UpstreamConfig
+	targets []*target
+	next    atomic.Uint64
+	cancel  context.CancelFunc
+}
+
+type target struct {
+	url     *url.URL
+	healthy atomic.Bool
+	active  atomic.Int64
+	// Consecutive probe results, only touched by the health check loop
+	successes int
+	failures  int
+}
+
+func newPool(route, id string, cfg config.UpstreamConfig) *pool {
+	p := &pool{route: route, id: id, config: cfg}
+	for _, raw := range cfg.Targets {
+		// Targets are validated when the config is parsed
+		parsed, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		t := &target{url: parsed}
+		t.healthy.Store(true)
+		p.targets = append(p.targets, t)
+	}
+	return p
+}
+
+// stop ends the health checks of the pool.
+func (p *pool) stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+}
+
+func (p *pool) pick() (*target, bool) {
+	candidates := make([]*target, 0, len(p.targets))
+	for _, t := range p.targets {
+		if t.healthy.Load() {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.targets
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	// Rotating the start breaks least connections ties in turn
+	start := int(p.next.Add(1)-1) % len(candidates)
+	if p.config.BalancerName() == config.BalancerRoundRobin {
+		return candidates[start], true
+	}
+
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		if t := candidates[(start+i)%len(candidates)]; t.active.Load() < best.active.Load() {
+			best = t
+		}
+	}
+	return best, true
+}
+
+// checkHealth probes the targets every health check interval until ctx is done.
+func (p *pool) checkHealth(ctx context.Context, client *http.Client, log ports.Logger) {
+	healthCheck := p.config.HealthCheck
+	ticker := time.NewTicker(healthCheck.IntervalDuration())
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, t := range p.targets {
+			wg.Add(1)
+			go func(t *target) {
+				defer wg.Done()
+				p.probe(ctx, client, log, t)
+			}(t)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *pool) probe(ctx context.Context, client *http.Client, log ports.Logger, t *target) {
+	healthCheck := p.config.HealthCheck
+	probeCtx, cancel := context.WithTimeout(ctx, healthCheck.TimeoutDuration())
+	defer cancel()
+
+	ok := false
+	req, err := http.NewRequestWithContext(probeCtx, http.MethodGet, t.url.JoinPath(healthCheck.Path).String(), nil)
+	if err == nil {
+		var resp *http.Response
+		if resp, err = client.Do(req); err == nil {
+			_ = resp.Body.Close()
+			ok = resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusBadRequest
+			if !ok {
+				err = fmt.Errorf("health check responded %d", resp.StatusCode)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		// The pool was stopped mid probe
+		return
+	}
+
+	healthyThreshold, unhealthyThreshold := healthCheck.Thresholds()
+	if ok {
+		t.successes, t.failures = t.successes+1, 0
+		if !t.healthy.Load() && t.successes >= healthyThreshold {
+			t.healthy.Store(true)
+			log.Info("upstream target healthy", ports.Field{Key: "route", Val: p.route}, ports.Field{Key: "target", Val: t.url.String()})
+		}
+		return
+	}
+
+	t.successes, t.failures = 0, t.failures+1
+	if t.healthy.Load() && t.failures >= unhealthyThreshold {
+		t.healthy.Store(false)
+		log.Error("upstream target unhealthy",
+			ports.Field{Key: "route", Val: p.route},
+			ports.Field{Key: "target", Val: t.url.String()},
+			ports.Field{Key: "err", Val: err.Error()},
+		)
+	}
+}
@@ -0,0 +1,162 @@
+package upstream
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+)
+
+// backend is an httptest server answering its name, and its health check with
+// the status held by healthy.
+type backend struct {
+	*httptest.Server
+	name    string
+	healthy atomic.Bool
+}
+
+func newBackend(t *testing.T, name string) *backend {
+	t.Helper()
+	b := &backend{name: name}
+	b.healthy.Store(true)
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && !b.healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, name)
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func targets(backends ...*backend) []string {
+	urls := make([]string, 0, len(backends))
+	for _, b := range backends {
+		urls = append(urls, b.URL)
+	}
+	return urls
+}
+
+// get returns the body of a GET of target.
+func get(t *testing.T, target string) string {
+	t.Helper()
+	resp, err := http.Get(target)
+	if err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read %s: %v", target, err)
+	}
+	return string(body)
+}
+
+func TestRoundRobin(t *testing.T) {
+	a, b, c := newBackend(t, "a"), newBackend(t, "b"), newBackend(t, "c")
+	p := newPool("api", "", config.UpstreamConfig{Targets: targets(a, b, c)})
+
+	var got string
+	for i := 0; i < 6; i++ {
+		target, ok := p.pick()
+		if !ok {
+			t.Fatalf("pick %d: no target", i)
+		}
+		got += get(t, target.url.String())
+	}
+	if got != "abcabc" {
+		t.Errorf("served by %q, want abcabc", got)
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	a, b, c := newBackend(t, "a"), newBackend(t, "b"), newBackend(t, "c")
+	p := newPool("api", "", config.UpstreamConfig{Targets: targets(a, b, c), Balancer: config.BalancerLeastConnections})
+
+	// Ties are broken in turn
+	var got string
+	for i := 0; i < 3; i++ {
+		target, _ := p.pick()
+		got += get(t, target.url.String())
+	}
+	if got != "abc" {
+		t.Errorf("idle targets picked in order %q, want abc", got)
+	}
+
+	// Busy targets are avoided whatever the turn
+	p.targets[0].active.Add(2)
+	p.targets[2].active.Add(1)
+	for i := 0; i < 3; i++ {
+		if target, _ := p.pick(); target != p.targets[1] {
+			t.Fatalf("pick %d: %s, want the idle target %s", i, target.url, p.targets[1].url)
+		}
+	}
+	p.targets[1].active.Add(3)
+	if target, _ := p.pick(); target != p.targets[2] {
+		t.Errorf("picked %s, want the least busy target %s", target.url, p.targets[2].url)
+	}
+}
+
+func TestHealthThresholds(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	p := newPool("api", "", config.UpstreamConfig{
+		Targets:     targets(a, b),
+		HealthCheck: config.HealthCheckConfig{Path: "/health", HealthyThreshold: 2, UnhealthyThreshold: 2},
+	})
+	ctx := context.Background()
+	client := &http.Client{}
+	probe := func() { p.probe(ctx, client, testutil.NopLogger{}, p.targets[0]) }
+	healthy := func() bool { return p.targets[0].healthy.Load() }
+
+	a.healthy.Store(false)
+	probe()
+	if !healthy() {
+		t.Fatal("target unhealthy after a single failed probe, want the unhealthy threshold of 2")
+	}
+	probe()
+	if healthy() {
+		t.Fatal("target healthy after 2 failed probes")
+	}
+	for i := 0; i < 4; i++ {
+		if target, _ := p.pick(); target != p.targets[1] {
+			t.Fatalf("pick %d: unhealthy target %s picked", i, target.url)
+		}
+	}
+
+	a.healthy.Store(true)
+	probe()
+	if healthy() {
+		t.Fatal("target healthy after a single passing probe, want the healthy threshold of 2")
+	}
+	probe()
+	if !healthy() {
+		t.Fatal("target unhealthy after 2 passing probes")
+	}
+}
+
+func TestNoHealthyTarget(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	p := newPool("api", "", config.UpstreamConfig{Targets: targets(a, b)})
+	for _, target := range p.targets {
+		target.healthy.Store(false)
+	}
+
+	// Requests are spread over every target rather than failed
+	var got string
+	for i := 0; i < 2; i++ {
+		target, ok := p.pick()
+		if !ok {
+			t.Fatalf("pick %d: no target", i)
+		}
+		got += get(t, target.url.String())
+	}
+	if got != "ab" {
+		t.Errorf("served by %q, want ab", got)
+	}
+}
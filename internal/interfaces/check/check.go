@@ -0,0 +1,208 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// errInvalidCheck marks checks rejected before reaching the limiter, answered
+// with codes.InvalidArgument over gRPC and 400 over HTTP.
+var errInvalidCheck = errors.New("invalid check")
+
+// maxBatchSize bounds the checks of a CheckBatch call.
+const maxBatchSize = 100
+
+// CheckRequest asks whether an action of Key may proceed under the limits of Route.
+type CheckRequest struct {
+	Route string `json:"route"`
+	// Key identifies the client, e.g. a tenant or a queue; the client IP is
+	// used when it is empty.
+	Key string `json:"key,omitempty"`
+	// IP is the client IP, for access lists and IP prefixes.
+	IP string `json:"ip,omitempty"`
+	// Cost is the units of the limits consumed, the default cost of the route
+	// when zero.
+	Cost int `json:"cost,omitempty"`
+}
+
+// CheckResponse holds the ports.RateLimitInfo fields of a check. DelayMs is the
+// time the caller waits before proceeding when a leaky bucket queues the check.
+type CheckResponse struct {
+	Allowed      bool         `json:"allowed"`
+	Limit        int          `json:"limit"`
+	Remaining    int          `json:"remaining"`
+	ResetTime    int64        `json:"reset_time"`
+	DelayMs      int64        `json:"delay_ms,omitempty"`
+	RetryAfterMs int64        `json:"retry_after_ms,omitempty"`
+	FailureMode  string       `json:"failure_mode,omitempty"`
+	RoutePolicy  string       `json:"route_policy,omitempty"`
+	Banned       bool         `json:"banned,omitempty"`
+	Blocked      bool         `json:"blocked,omitempty"`
+	Policy       string       `json:"policy,omitempty"`
+	Policies     []PolicyView `json:"policies,omitempty"`
+}
+
+// PolicyView is a limit of the route, PeriodMs being zero for in-flight limits.
+type PolicyView struct {
+	Name     string `json:"name"`
+	Limit    int    `json:"limit"`
+	PeriodMs int64  `json:"period_ms,omitempty"`
+	InFlight bool   `json:"in_flight,omitempty"`
+}
+
+type CheckBatchRequest struct {
+	Checks []CheckRequest `json:"checks"`
+}
+
+// CheckBatchResponse holds the results of the checks, in order.
+type CheckBatchResponse struct {
+	Results []CheckResponse `json:"results"`
+}
+
+// Server answers checks of internal services against the rate limiting rules
+// of Consul, over gRPC and JSON over HTTP. Checks return at once, leaving the
+// delay of leaky buckets to the caller, and can't see work complete, so routes
+// with concurrency limits are refused.
+type Server struct {
+	limiter *service.LimiterService
+	logger  ports.Logger
+	tracer  ports.Tracer
+}
+
+func NewServer(limiter *service.LimiterService, log ports.Logger, tracer ports.Tracer) *Server {
+	return &Server{
+		limiter: limiter,
+		logger:  log,
+		tracer:  tracer,
+	}
+}
+
+func (s *Server) Check(ctx context.Context, req *CheckRequest) (*CheckResponse, error) {
+	if err := s.validate(*req); err != nil {
+		return nil, err
+	}
+
+	ctx = s.tracer.Extract(ctx, incomingHeader(ctx))
+	ctx, span := s.tracer.Start(ctx, "CheckServer.Check", ports.SpanKindServer,
+		ports.Field{Key: "rate_limiter.route", Val: req.Route})
+	defer span.End()
+
+	resp, err := s.check(ctx, *req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CheckBatch runs the checks in order, each consuming its cost whatever the
+// result of the others.
+func (s *Server) CheckBatch(ctx context.Context, req *CheckBatchRequest) (*CheckBatchResponse, error) {
+	if len(req.Checks) > maxBatchSize {
+		return nil, fmt.Errorf("%w: at most %d checks per batch, got %d", errInvalidCheck, maxBatchSize, len(req.Checks))
+	}
+	for i, check := range req.Checks {
+		if err := s.validate(check); err != nil {
+			return nil, fmt.Errorf("check %d: %w", i, err)
+		}
+	}
+
+	ctx = s.tracer.Extract(ctx, incomingHeader(ctx))
+	ctx, span := s.tracer.Start(ctx, "CheckServer.CheckBatch", ports.SpanKindServer,
+		ports.Field{Key: "rate_limiter.checks", Val: len(req.Checks)})
+	defer span.End()
+
+	resp := &CheckBatchResponse{Results: make([]CheckResponse, 0, len(req.Checks))}
+	for _, check := range req.Checks {
+		result, err := s.check(ctx, check)
+		if err != nil {
+			span.RecordError(err)
+			return nil, err
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// check decides req, its cost being bounded to the cost config of the route by
+// the limiter. Keys are prefixed with config.CallerKeyPrefix.
+func (s *Server) check(ctx context.Context, req CheckRequest) (CheckResponse, error) {
+	cost := req.Cost
+	if cost == 0 {
+		routeConfig, _ := s.limiter.Route(req.Route)
+		cost = routeConfig.Cost.Resolve("", "", "")
+	}
+	var clientKey string
+	if req.Key != "" {
+		clientKey = config.CallerKeyPrefix + req.Key
+	}
+
+	info, err := s.limiter.ReserveWithInfo(ctx, req.IP, req.Route, clientKey, cost)
+	if err != nil {
+		s.logger.Error("limiter check failed", ports.Field{Key: "route", Val: req.Route}, ports.Field{Key: "err", Val: err})
+		return CheckResponse{}, err
+	}
+	// Routes given concurrency limits since the check was validated get their
+	// slots back
+	if info.Lease != nil {
+		if err := info.Lease.Release(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Error("failed to release concurrency lease", ports.Field{Key: "route", Val: req.Route}, ports.Field{Key: "err", Val: err})
+		}
+	}
+
+	s.logger.Info("check answered",
+		ports.Field{Key: "route", Val: req.Route},
+		ports.Field{Key: "client key", Val: req.Key},
+		ports.Field{Key: "cost", Val: cost},
+		ports.Field{Key: "allowed", Val: info.Allowed},
+	)
+	return newCheckResponse(info), nil
+}
+
+func (s *Server) validate(req CheckRequest) error {
+	switch {
+	case req.Route == "":
+		return fmt.Errorf("%w: route is required", errInvalidCheck)
+	case req.Key == "" && req.IP == "":
+		return fmt.Errorf("%w: key or ip is required", errInvalidCheck)
+	case req.Cost < 0:
+		return fmt.Errorf("%w: cost must not be negative, got %d", errInvalidCheck, req.Cost)
+	}
+
+	routeConfig, _ := s.limiter.Route(req.Route)
+	for _, limit := range routeConfig.Limits {
+		if limit.Algorithm == config.AlgorithmConcurrency {
+			return fmt.Errorf("%w: route %q has a concurrency limit, which checks can't release", errInvalidCheck, req.Route)
+		}
+	}
+	return nil
+}
+
+func newCheckResponse(info ports.RateLimitInfo) CheckResponse {
+	resp := CheckResponse{
+		Allowed:      info.Allowed,
+		Limit:        info.Limit,
+		Remaining:    info.Remaining,
+		ResetTime:    info.ResetTime,
+		DelayMs:      info.Delay.Milliseconds(),
+		RetryAfterMs: info.RetryAfter.Milliseconds(),
+		FailureMode:  info.FailureMode,
+		RoutePolicy:  info.RoutePolicy,
+		Banned:       info.Banned,
+		Blocked:      info.Blocked,
+		Policy:       info.Policy,
+	}
+	for _, policy := range info.Policies {
+		view := PolicyView{Name: policy.Name, Limit: policy.Quota.Limit, InFlight: policy.Quota.InFlight}
+		if !policy.Quota.InFlight {
+			view.PeriodMs = policy.Quota.Period.Milliseconds()
+		}
+		resp.Policies = append(resp.Policies, view)
+	}
+	return resp
+}
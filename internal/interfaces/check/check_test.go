@@ -0,0 +1,251 @@
+package check_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/check"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+	checkv1 "github.com/SilentPlaces/rate_limiter/proto/ratelimiter/check/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// routesJSON limits jobs to 2 units a minute, costing at most 2, queues at most
+// 5 checks of queue draining one a second, and limits workers in flight.
+const routesJSON = `{
+  "routes": {
+    "jobs": {
+      "algorithm": "fixed_window",
+      "limit": 2,
+      "window": 60,
+      "cost": { "max": 2 }
+    },
+    "queue": {
+      "algorithm": "leaky_bucket",
+      "capacity": 5,
+      "leak_rate": 1
+    },
+    "workers": {
+      "algorithm": "concurrency",
+      "limit": 1,
+      "lease_ttl": 60
+    }
+  }
+}`
+
+func newServer(t *testing.T) *check.Server {
+	t.Helper()
+	limiterSvc := testutil.NewLimiterService(t, routesJSON)
+	return check.NewServer(limiterSvc.LimiterService, testutil.NopLogger{}, limiterSvc.Tracer)
+}
+
+func newClient(t *testing.T) checkv1.RateLimitCheckClient {
+	t.Helper()
+	server := newServer(t)
+	conn := testutil.Dial(t, func(s *grpc.Server) {
+		check.RegisterServer(s, server)
+	})
+	return checkv1.NewRateLimitCheckClient(conn)
+}
+
+func TestCheckConsumesCost(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	resp, err := client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "tenant-42"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !resp.GetAllowed() || resp.GetLimit() != 2 || resp.GetRemaining() != 1 {
+		t.Fatalf("allowed %v, limit %d, remaining %d, want allowed with 1 of 2 remaining",
+			resp.GetAllowed(), resp.GetLimit(), resp.GetRemaining())
+	}
+
+	// Above the maximum cost of 2, a check consumes 2 and fits a fresh limit
+	resp, err = client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "tenant-7", Cost: 100})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !resp.GetAllowed() || resp.GetRemaining() != 0 {
+		t.Fatalf("allowed %v with %d remaining, want allowed with 0 remaining", resp.GetAllowed(), resp.GetRemaining())
+	}
+
+	resp, err = client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "tenant-7"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if resp.GetAllowed() {
+		t.Fatal("check over limit allowed")
+	}
+	if resp.GetResetTime() < time.Now().Unix() {
+		t.Errorf("reset time %d, want the end of the window", resp.GetResetTime())
+	}
+}
+
+func TestCheckPrefixesCallerKeys(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	// A key naming an IP exhausts a limit of its own, not that of the IP
+	for i := 0; i < 2; i++ {
+		if _, err := client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "203.0.113.1"}); err != nil {
+			t.Fatalf("check %d: %v", i, err)
+		}
+	}
+	resp, err := client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "203.0.113.1"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if resp.GetAllowed() {
+		t.Fatal("key over limit allowed")
+	}
+
+	resp, err = client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Ip: "203.0.113.1"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if !resp.GetAllowed() || resp.GetRemaining() != 1 {
+		t.Errorf("IP allowed %v with %d remaining, want allowed with 1 remaining", resp.GetAllowed(), resp.GetRemaining())
+	}
+}
+
+func TestCheckReturnsDelayWithoutWaiting(t *testing.T) {
+	client := newClient(t)
+
+	start := time.Now()
+	resp, err := client.CheckBatch(context.Background(), &checkv1.CheckBatchRequest{Checks: []*checkv1.CheckRequest{
+		{Route: "queue", Key: "consumer"},
+		{Route: "queue", Key: "consumer"},
+		{Route: "queue", Key: "consumer"},
+	}})
+	if err != nil {
+		t.Fatalf("check batch: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("batch answered in %v, want the delays left to the caller", elapsed)
+	}
+
+	var previous int64 = -1
+	for i, result := range resp.GetResults() {
+		if !result.GetAllowed() {
+			t.Fatalf("check %d denied, want queued", i)
+		}
+		if result.GetDelayMs() <= previous {
+			t.Errorf("check %d: delay %dms, want more than %dms", i, result.GetDelayMs(), previous)
+		}
+		previous = result.GetDelayMs()
+	}
+	if previous < 1000 {
+		t.Errorf("last delay %dms, want at least a second at one check a second", previous)
+	}
+}
+
+func TestCheckInvalid(t *testing.T) {
+	client := newClient(t)
+
+	tests := []struct {
+		name string
+		req  *checkv1.CheckRequest
+	}{
+		{name: "no route", req: &checkv1.CheckRequest{Key: "tenant-42"}},
+		{name: "no client", req: &checkv1.CheckRequest{Route: "jobs"}},
+		{name: "negative cost", req: &checkv1.CheckRequest{Route: "jobs", Key: "tenant-42", Cost: -1}},
+		{name: "concurrency route", req: &checkv1.CheckRequest{Route: "workers", Key: "tenant-42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Check(context.Background(), tt.req)
+			if code := status.Code(err); code != codes.InvalidArgument {
+				t.Errorf("code %v (%v), want InvalidArgument", code, err)
+			}
+		})
+	}
+}
+
+func TestCheckBatch(t *testing.T) {
+	client := newClient(t)
+	ctx := context.Background()
+
+	checks := make([]*checkv1.CheckRequest, 3)
+	for i := range checks {
+		checks[i] = &checkv1.CheckRequest{Route: "jobs", Key: "tenant-42"}
+	}
+	resp, err := client.CheckBatch(ctx, &checkv1.CheckBatchRequest{Checks: checks})
+	if err != nil {
+		t.Fatalf("check batch: %v", err)
+	}
+	var allowed []bool
+	for _, result := range resp.GetResults() {
+		allowed = append(allowed, result.GetAllowed())
+	}
+	if want := []bool{true, true, false}; !slices.Equal(allowed, want) {
+		t.Errorf("allowed %v, want %v in order", allowed, want)
+	}
+
+	// An invalid check fails the whole batch before any is made
+	_, err = client.CheckBatch(ctx, &checkv1.CheckBatchRequest{Checks: []*checkv1.CheckRequest{
+		{Route: "jobs", Key: "tenant-7"},
+		{Route: "jobs"},
+	}})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Fatalf("code %v, want InvalidArgument", code)
+	}
+	result, err := client.Check(ctx, &checkv1.CheckRequest{Route: "jobs", Key: "tenant-7"})
+	if err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if result.GetRemaining() != 1 {
+		t.Errorf("remaining %d after a failed batch, want 1", result.GetRemaining())
+	}
+}
+
+func TestCheckBatchTooLarge(t *testing.T) {
+	client := newClient(t)
+
+	checks := make([]*checkv1.CheckRequest, 101)
+	for i := range checks {
+		checks[i] = &checkv1.CheckRequest{Route: "jobs", Key: "tenant-42"}
+	}
+	_, err := client.CheckBatch(context.Background(), &checkv1.CheckBatchRequest{Checks: checks})
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("code %v, want InvalidArgument", code)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	handler := newServer(t).Handler()
+
+	tests := []struct {
+		name, path, body string
+		wantStatus       int
+		wantBody         string
+	}{
+		{name: "check", path: "/v1/check", body: `{"route": "jobs", "key": "tenant-42"}`,
+			wantStatus: http.StatusOK, wantBody: `"remaining":1`},
+		{name: "batch", path: "/v1/check/batch", body: `{"checks": [{"route": "jobs", "key": "tenant-7", "cost": 2}]}`,
+			wantStatus: http.StatusOK, wantBody: `"results":[{"allowed":true`},
+		{name: "invalid check", path: "/v1/check", body: `{"route": "workers", "key": "tenant-42"}`,
+			wantStatus: http.StatusBadRequest, wantBody: "concurrency limit"},
+		{name: "malformed body", path: "/v1/check", body: `{"route":`,
+			wantStatus: http.StatusBadRequest, wantBody: "invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %q, want it to contain %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	checkv1 "github.com/SilentPlaces/rate_limiter/proto/ratelimiter/check/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// RegisterServer serves s on gs as the ratelimiter.check.v1.RateLimitCheck
+// service of proto/ratelimiter/check/v1/check.proto.
+func RegisterServer(gs *grpc.Server, s *Server) {
+	checkv1.RegisterRateLimitCheckServer(gs, &grpcServer{server: s})
+}
+
+// grpcServer adapts Server to the protobuf messages of the gRPC service.
+type grpcServer struct {
+	checkv1.UnimplementedRateLimitCheckServer
+	server *Server
+}
+
+func (g *grpcServer) Check(ctx context.Context, req *checkv1.CheckRequest) (*checkv1.CheckResponse, error) {
+	resp, err := g.server.Check(ctx, checkRequestFromProto(req))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return checkResponseToProto(*resp), nil
+}
+
+func (g *grpcServer) CheckBatch(ctx context.Context, req *checkv1.CheckBatchRequest) (*checkv1.CheckBatchResponse, error) {
+	batch := &CheckBatchRequest{Checks: make([]CheckRequest, 0, len(req.GetChecks()))}
+	for _, check := range req.GetChecks() {
+		batch.Checks = append(batch.Checks, *checkRequestFromProto(check))
+	}
+
+	resp, err := g.server.CheckBatch(ctx, batch)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	results := make([]*checkv1.CheckResponse, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		results = append(results, checkResponseToProto(result))
+	}
+	return &checkv1.CheckBatchResponse{Results: results}, nil
+}
+
+func checkRequestFromProto(req *checkv1.CheckRequest) *CheckRequest {
+	return &CheckRequest{
+		Route: req.GetRoute(),
+		Key:   req.GetKey(),
+		IP:    req.GetIp(),
+		Cost:  int(req.GetCost()),
+	}
+}
+
+func checkResponseToProto(resp CheckResponse) *checkv1.CheckResponse {
+	msg := &checkv1.CheckResponse{
+		Allowed:      resp.Allowed,
+		Limit:        int64(resp.Limit),
+		Remaining:    int64(resp.Remaining),
+		ResetTime:    resp.ResetTime,
+		DelayMs:      resp.DelayMs,
+		RetryAfterMs: resp.RetryAfterMs,
+		FailureMode:  resp.FailureMode,
+		RoutePolicy:  resp.RoutePolicy,
+		Banned:       resp.Banned,
+		Blocked:      resp.Blocked,
+		Policy:       resp.Policy,
+	}
+	for _, policy := range resp.Policies {
+		msg.Policies = append(msg.Policies, &checkv1.Policy{
+			Name:     policy.Name,
+			Limit:    int64(policy.Limit),
+			PeriodMs: policy.PeriodMs,
+			InFlight: policy.InFlight,
+		})
+	}
+	return msg
+}
+
+func grpcError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errInvalidCheck):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, "limiter check failed")
+	}
+}
+
+// incomingHeader returns the metadata of an incoming gRPC call as HTTP headers,
+// to extract the trace context of the caller. It is empty for HTTP requests.
+func incomingHeader(ctx context.Context) http.Header {
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for name, values := range md {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	return header
+}
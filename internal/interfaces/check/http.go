@@ -0,0 +1,75 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// maxBodyBytes bounds the bodies of JSON check requests.
+const maxBodyBytes = 1 << 20
+
+// Handler serves the check API as JSON over HTTP:
+// POST /v1/check takes a CheckRequest and POST /v1/check/batch a CheckBatchRequest.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/check", s.serveCheck)
+	mux.HandleFunc("POST /v1/check/batch", s.serveCheckBatch)
+	return mux
+}
+
+func (s *Server) serveCheck(w http.ResponseWriter, r *http.Request) {
+	var req CheckRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	ctx := s.tracer.Extract(r.Context(), r.Header)
+	resp, err := s.Check(ctx, &req)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) serveCheckBatch(w http.ResponseWriter, r *http.Request) {
+	var req CheckBatchRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	ctx := s.tracer.Extract(r.Context(), r.Header)
+	resp, err := s.CheckBatch(ctx, &req)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(v); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidCheck) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	http.Error(w, "limiter check failed", http.StatusInternalServerError)
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.logger.Error("failed to write check response", ports.Field{Key: "err", Val: err})
+	}
+}
@@ -0,0 +1,157 @@
+package envoy
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	typev3 "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// AuthorizationServer implements the Envoy ext_authz v3 Authorization service.
+// The request of a CheckRequest is decided by handler, a decision handler
+// answering 2xx to allowed requests: allowed requests are let through with the
+// rate limit headers added to their response, and the rejection response of
+// rejected ones is sent by Envoy to the client.
+type AuthorizationServer struct {
+	authv3.UnimplementedAuthorizationServer
+	handler http.Handler
+}
+
+func NewAuthorizationServer(handler http.Handler) *AuthorizationServer {
+	return &AuthorizationServer{handler: handler}
+}
+
+func (s *AuthorizationServer) Check(ctx context.Context, req *authv3.CheckRequest) (*authv3.CheckResponse, error) {
+	r := checkedRequest(ctx, req)
+	w := newResponseBuffer()
+	s.handler.ServeHTTP(w, r)
+
+	if w.status >= http.StatusOK && w.status < http.StatusMultipleChoices {
+		return &authv3.CheckResponse{
+			Status: &status.Status{Code: int32(codes.OK)},
+			HttpResponse: &authv3.CheckResponse_OkResponse{
+				OkResponse: &authv3.OkHttpResponse{ResponseHeadersToAdd: headerOptions(w.header)},
+			},
+		}, nil
+	}
+
+	return &authv3.CheckResponse{
+		Status: &status.Status{Code: int32(deniedCode(w.status))},
+		HttpResponse: &authv3.CheckResponse_DeniedResponse{
+			DeniedResponse: &authv3.DeniedHttpResponse{
+				Status:  &typev3.HttpStatus{Code: typev3.StatusCode(w.status)},
+				Headers: headerOptions(w.header),
+				Body:    w.body.String(),
+			},
+		},
+	}, nil
+}
+
+// checkedRequest rebuilds the HTTP request described by the attributes of req,
+// its peer being the downstream peer of Envoy.
+func checkedRequest(ctx context.Context, req *authv3.CheckRequest) *http.Request {
+	attributes := req.GetAttributes()
+	httpAttributes := attributes.GetRequest().GetHttp()
+
+	r := (&http.Request{
+		Method:     httpAttributes.GetMethod(),
+		Host:       httpAttributes.GetHost(),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header, len(httpAttributes.GetHeaders())),
+		URL:        &url.URL{Path: "/"},
+		RequestURI: httpAttributes.GetPath(),
+		RemoteAddr: peerAddress(attributes.GetSource()),
+	}).WithContext(ctx)
+	if r.Method == "" {
+		r.Method = http.MethodGet
+	}
+	if parsed, err := url.ParseRequestURI(httpAttributes.GetPath()); err == nil {
+		r.URL = parsed
+	}
+
+	for name, value := range httpAttributes.GetHeaders() {
+		// Pseudo-headers are described by the attributes themselves
+		if len(name) > 0 && name[0] == ':' {
+			continue
+		}
+		r.Header.Set(name, value)
+	}
+	return r
+}
+
+// peerAddress returns the host:port of the socket address of peer.
+func peerAddress(peer *authv3.AttributeContext_Peer) string {
+	socket := peer.GetAddress().GetSocketAddress()
+	if socket == nil {
+		return ""
+	}
+	return net.JoinHostPort(socket.GetAddress(), strconv.FormatUint(uint64(socket.GetPortValue()), 10))
+}
+
+func headerOptions(header http.Header) []*corev3.HeaderValueOption {
+	var options []*corev3.HeaderValueOption
+	for name, values := range header {
+		for _, value := range values {
+			options = append(options, &corev3.HeaderValueOption{
+				Header: &corev3.HeaderValue{Key: name, Value: value},
+			})
+		}
+	}
+	return options
+}
+
+// deniedCode returns the gRPC status of a CheckResponse denying a request with
+// the HTTP status code. Envoy only tells it apart from OK; it shows in logs.
+func deniedCode(code int) codes.Code {
+	switch code {
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusInternalServerError:
+		return codes.Internal
+	default:
+		return codes.PermissionDenied
+	}
+}
+
+// responseBuffer keeps the response of a decision for the CheckResponse.
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.wroteHeader {
+		return
+	}
+	b.status = status
+	b.wroteHeader = true
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
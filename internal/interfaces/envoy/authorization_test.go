@@ -0,0 +1,119 @@
+package envoy_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/envoy"
+	handler "github.com/SilentPlaces/rate_limiter/internal/interfaces/http"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	authv3 "github.com/envoyproxy/go-control-plane/envoy/service/auth/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func newAuthorizationClient(t *testing.T) authv3.AuthorizationClient {
+	t.Helper()
+
+	limiterSvc := testutil.NewLimiterService(t, routesJSON)
+	clientIP, err := handler.NewClientIPResolver(nil, nil)
+	if err != nil {
+		t.Fatalf("client IP resolver: %v", err)
+	}
+	headers, err := handler.NewRateLimitHeaders(nil)
+	if err != nil {
+		t.Fatalf("rate limit headers: %v", err)
+	}
+	h := &handler.HTTPHandler{
+		LimiterService: limiterSvc.LimiterService,
+		Logger:         testutil.NopLogger{},
+		Metrics:        limiterSvc.Metrics,
+		Tracer:         limiterSvc.Tracer,
+		ClientIP:       clientIP,
+		Headers:        headers,
+	}
+
+	conn := testutil.Dial(t, func(s *grpc.Server) {
+		authv3.RegisterAuthorizationServer(s, envoy.NewAuthorizationServer(handler.NewDecisionHandler(h, 0)))
+	})
+	return authv3.NewAuthorizationClient(conn)
+}
+
+// checkRequest describes a GET of path by the client at ip.
+func checkRequest(ip, path string) *authv3.CheckRequest {
+	return &authv3.CheckRequest{
+		Attributes: &authv3.AttributeContext{
+			Source: &authv3.AttributeContext_Peer{
+				Address: &corev3.Address{Address: &corev3.Address_SocketAddress{
+					SocketAddress: &corev3.SocketAddress{
+						Address:       ip,
+						PortSpecifier: &corev3.SocketAddress_PortValue{PortValue: 50000},
+					},
+				}},
+			},
+			Request: &authv3.AttributeContext_Request{
+				Http: &authv3.AttributeContext_HttpRequest{Method: http.MethodGet, Host: "example.com", Path: path},
+			},
+		},
+	}
+}
+
+func header(options []*corev3.HeaderValueOption, name string) string {
+	for _, option := range options {
+		if http.CanonicalHeaderKey(option.GetHeader().GetKey()) == http.CanonicalHeaderKey(name) {
+			return option.GetHeader().GetValue()
+		}
+	}
+	return ""
+}
+
+func TestAuthorization(t *testing.T) {
+	tests := []struct {
+		name string
+		// requests are checked before the request, by the same client.
+		requests   int
+		ip, path   string
+		wantCode   codes.Code
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "within limit", ip: "203.0.113.1", path: "/api/users", wantCode: codes.OK},
+		{name: "over limit", requests: 2, ip: "203.0.113.1", path: "/api/users", wantCode: codes.ResourceExhausted, wantStatus: http.StatusTooManyRequests},
+		{name: "denied client", ip: "192.0.2.7", path: "/api/users", wantCode: codes.PermissionDenied, wantStatus: http.StatusForbidden},
+		{name: "unknown route", ip: "203.0.113.1", path: "/other", wantCode: codes.PermissionDenied, wantStatus: http.StatusForbidden, wantBody: "unknown route\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newAuthorizationClient(t)
+			ctx := context.Background()
+			for i := 0; i < tt.requests; i++ {
+				if _, err := client.Check(ctx, checkRequest(tt.ip, tt.path)); err != nil {
+					t.Fatalf("check %d: %v", i, err)
+				}
+			}
+
+			resp, err := client.Check(ctx, checkRequest(tt.ip, tt.path))
+			if err != nil {
+				t.Fatalf("check: %v", err)
+			}
+			if code := codes.Code(resp.GetStatus().GetCode()); code != tt.wantCode {
+				t.Fatalf("status %v, want %v", code, tt.wantCode)
+			}
+			if tt.wantCode == codes.OK {
+				if got := header(resp.GetOkResponse().GetResponseHeadersToAdd(), "X-RateLimit-Remaining"); got != "1" {
+					t.Errorf("X-RateLimit-Remaining %q, want 1", got)
+				}
+				return
+			}
+			denied := resp.GetDeniedResponse()
+			if got := int(denied.GetStatus().GetCode()); got != tt.wantStatus {
+				t.Errorf("denied with HTTP status %d, want %d", got, tt.wantStatus)
+			}
+			if tt.wantBody != "" && denied.GetBody() != tt.wantBody {
+				t.Errorf("denied with body %q, want %q", denied.GetBody(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package envoy
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	handler "github.com/SilentPlaces/rate_limiter/internal/interfaces/http"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Descriptor entries with a meaning of their own. The values of other entries
+// make up the client key.
+const (
+	// EntryRoute names the route of the descriptor, e.g. set by a generic_key
+	// action with descriptor_key "route".
+	EntryRoute = "route"
+	// EntryGenericKey is the default key of generic_key actions, read as the
+	// route when there is no EntryRoute entry.
+	EntryGenericKey = "generic_key"
+	// EntryRemoteAddress is the client IP set by remote_address actions.
+	EntryRemoteAddress = "remote_address"
+)
+
+// RateLimitServer implements the Envoy ratelimit v3 RateLimitService. Every
+// descriptor is checked against the limits of its route, the descriptor being
+// over limit when the request would be rejected.
+type RateLimitServer struct {
+	rlsv3.UnimplementedRateLimitServiceServer
+	limiter *service.LimiterService
+	logger  ports.Logger
+	tracer  ports.Tracer
+	headers handler.RateLimitHeaders
+}
+
+func NewRateLimitServer(limiter *service.LimiterService, log ports.Logger, tracer ports.Tracer, headers handler.RateLimitHeaders) *RateLimitServer {
+	return &RateLimitServer{
+		limiter: limiter,
+		logger:  log,
+		tracer:  tracer,
+		headers: headers,
+	}
+}
+
+func (s *RateLimitServer) ShouldRateLimit(ctx context.Context, req *rlsv3.RateLimitRequest) (*rlsv3.RateLimitResponse, error) {
+	ctx = s.tracer.Extract(ctx, incomingHeader(ctx))
+	ctx, span := s.tracer.Start(ctx, "RateLimitServer.ShouldRateLimit", ports.SpanKindServer,
+		ports.Field{Key: "rate_limiter.domain", Val: req.GetDomain()},
+		ports.Field{Key: "rate_limiter.descriptors", Val: len(req.GetDescriptors())})
+	defer span.End()
+
+	resp := &rlsv3.RateLimitResponse{OverallCode: rlsv3.RateLimitResponse_OK}
+	var reported *ports.RateLimitInfo
+	now := time.Now()
+
+	for _, descriptor := range req.GetDescriptors() {
+		route, ip, clientKey := descriptorKeys(req.GetDomain(), descriptor)
+		routeConfig, _ := s.limiter.Route(route)
+		cost, checkOnly := hitsAddend(req, descriptor, routeConfig)
+
+		var info ports.RateLimitInfo
+		var err error
+		if checkOnly {
+			info, err = s.limiter.CheckWithInfo(ctx, ip, route, clientKey)
+		} else {
+			info, err = s.limiter.AllowWithInfo(ctx, ip, route, clientKey, cost)
+		}
+		if err != nil {
+			s.logger.Error("limiter check failed", ports.Field{Key: "route", Val: route}, ports.Field{Key: "err", Val: err})
+			span.RecordError(err)
+			return nil, err
+		}
+		// The request isn't seen completing, so in-flight slots are freed
+		// right away.
+		if info.Lease != nil {
+			if err := info.Lease.Release(context.WithoutCancel(ctx)); err != nil {
+				s.logger.Error("failed to release concurrency lease", ports.Field{Key: "route", Val: route}, ports.Field{Key: "err", Val: err})
+			}
+		}
+
+		result := descriptorStatus(info, now)
+		if result.Code == rlsv3.RateLimitResponse_OVER_LIMIT {
+			resp.OverallCode = rlsv3.RateLimitResponse_OVER_LIMIT
+			if reported == nil || reported.Allowed {
+				reported = &info
+			}
+		} else if reported == nil {
+			reported = &info
+		}
+		resp.Statuses = append(resp.Statuses, result)
+
+		s.logger.Info("descriptor checked",
+			ports.Field{Key: "ip", Val: ip},
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "client key", Val: clientKey},
+			ports.Field{Key: "cost", Val: cost},
+			ports.Field{Key: "code", Val: result.Code.String()},
+		)
+	}
+
+	// Headers report the first descriptor over limit, or else the first descriptor
+	if reported != nil {
+		header := make(http.Header)
+		s.headers.Write(header, *reported, now)
+		for name, values := range header {
+			for _, value := range values {
+				resp.ResponseHeadersToAdd = append(resp.ResponseHeadersToAdd, &corev3.HeaderValue{Key: name, Value: value})
+			}
+		}
+	}
+
+	span.SetAttributes(ports.Field{Key: "rate_limiter.code", Val: resp.OverallCode.String()})
+	return resp, nil
+}
+
+// descriptorKeys returns the route, client IP and client key of descriptor. The
+// route is the domain when the descriptor has no route entry, and the client key
+// is empty, meaning the client IP, when it has no other entries. Client keys are
+// prefixed with config.DescriptorKeyPrefix.
+func descriptorKeys(domain string, descriptor *ratelimitv3.RateLimitDescriptor) (route, ip, clientKey string) {
+	var genericKey string
+	var values []string
+	for _, entry := range descriptor.GetEntries() {
+		switch entry.GetKey() {
+		case EntryRoute:
+			route = entry.GetValue()
+		case EntryGenericKey:
+			genericKey = entry.GetValue()
+		case EntryRemoteAddress:
+			ip = entry.GetValue()
+		default:
+			values = append(values, entry.GetValue())
+		}
+	}
+
+	switch {
+	case route != "":
+	case genericKey != "":
+		route = genericKey
+	default:
+		route = domain
+	}
+	if len(values) > 0 {
+		clientKey = config.DescriptorKeyPrefix + strings.Join(values, ":")
+	}
+	return route, ip, clientKey
+}
+
+// hitsAddend returns the cost of descriptor: its own hits addend, else the hits
+// addend of the request, else the default cost of the route. An explicit hits
+// addend of zero checks the descriptor without consuming anything. Costs are
+// bounded to the cost config of the route by the limiter.
+func hitsAddend(req *rlsv3.RateLimitRequest, descriptor *ratelimitv3.RateLimitDescriptor, routeConfig config.RouteConfig) (cost int, checkOnly bool) {
+	if addend := descriptor.GetHitsAddend(); addend != nil {
+		return int(min(addend.GetValue(), math.MaxInt32)), addend.GetValue() == 0
+	}
+	if req.GetHitsAddend() > 0 {
+		return int(min(req.GetHitsAddend(), math.MaxInt32)), false
+	}
+	return routeConfig.Cost.Resolve("", "", ""), false
+}
+
+func descriptorStatus(info ports.RateLimitInfo, now time.Time) *rlsv3.RateLimitResponse_DescriptorStatus {
+	result := &rlsv3.RateLimitResponse_DescriptorStatus{Code: rlsv3.RateLimitResponse_OK}
+	if !info.Allowed || info.Blocked || info.RoutePolicy == config.UnknownRouteReject {
+		result.Code = rlsv3.RateLimitResponse_OVER_LIMIT
+	}
+
+	if info.Limit > 0 {
+		result.LimitRemaining = uint32(max(info.Remaining, 0))
+		for _, policy := range info.Policies {
+			if policy.Name == info.Policy {
+				result.CurrentLimit = currentLimit(policy)
+			}
+		}
+	}
+	if info.ResetTime > 0 {
+		result.DurationUntilReset = durationpb.New(time.Duration(max(info.ResetTime-now.Unix(), 0)) * time.Second)
+	}
+	return result
+}
+
+// currentLimit describes policy when its window is a whole unit, the only
+// windows the ratelimit API can describe.
+func currentLimit(policy ports.LimitPolicy) *rlsv3.RateLimitResponse_RateLimit {
+	if policy.Quota.InFlight {
+		return nil
+	}
+
+	units := map[time.Duration]rlsv3.RateLimitResponse_RateLimit_Unit{
+		time.Second:        rlsv3.RateLimitResponse_RateLimit_SECOND,
+		time.Minute:        rlsv3.RateLimitResponse_RateLimit_MINUTE,
+		time.Hour:          rlsv3.RateLimitResponse_RateLimit_HOUR,
+		24 * time.Hour:     rlsv3.RateLimitResponse_RateLimit_DAY,
+		7 * 24 * time.Hour: rlsv3.RateLimitResponse_RateLimit_WEEK,
+	}
+	unit, ok := units[policy.Quota.Period]
+	if !ok {
+		return nil
+	}
+	return &rlsv3.RateLimitResponse_RateLimit{
+		Name:            policy.Name,
+		RequestsPerUnit: uint32(policy.Quota.Limit),
+		Unit:            unit,
+	}
+}
+
+// incomingHeader returns the metadata of the incoming call as HTTP headers, to
+// extract the trace context Envoy propagates.
+func incomingHeader(ctx context.Context) http.Header {
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for name, values := range md {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	return header
+}
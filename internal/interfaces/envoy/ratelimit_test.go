@@ -0,0 +1,146 @@
+package envoy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/interfaces/envoy"
+	handler "github.com/SilentPlaces/rate_limiter/internal/interfaces/http"
+	"github.com/SilentPlaces/rate_limiter/internal/testutil"
+	ratelimitv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/common/ratelimit/v3"
+	rlsv3 "github.com/envoyproxy/go-control-plane/envoy/service/ratelimit/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newRateLimitClient(t *testing.T) rlsv3.RateLimitServiceClient {
+	t.Helper()
+
+	limiterSvc := testutil.NewLimiterService(t, routesJSON)
+	headers, err := handler.NewRateLimitHeaders(nil)
+	if err != nil {
+		t.Fatalf("rate limit headers: %v", err)
+	}
+
+	conn := testutil.Dial(t, func(s *grpc.Server) {
+		rlsv3.RegisterRateLimitServiceServer(s, envoy.NewRateLimitServer(limiterSvc.LimiterService, testutil.NopLogger{}, limiterSvc.Tracer, headers))
+	})
+	return rlsv3.NewRateLimitServiceClient(conn)
+}
+
+// rateLimitRequest checks route for the client at ip, hitsAddend being the
+// hits addend of the descriptor when not nil.
+func rateLimitRequest(route, ip string, hitsAddend *wrapperspb.UInt64Value) *rlsv3.RateLimitRequest {
+	return &rlsv3.RateLimitRequest{
+		Domain: "edge",
+		Descriptors: []*ratelimitv3.RateLimitDescriptor{{
+			Entries: []*ratelimitv3.RateLimitDescriptor_Entry{
+				{Key: envoy.EntryRoute, Value: route},
+				{Key: envoy.EntryRemoteAddress, Value: ip},
+			},
+			HitsAddend: hitsAddend,
+		}},
+	}
+}
+
+func shouldRateLimit(t *testing.T, client rlsv3.RateLimitServiceClient, req *rlsv3.RateLimitRequest) *rlsv3.RateLimitResponse {
+	t.Helper()
+	resp, err := client.ShouldRateLimit(context.Background(), req)
+	if err != nil {
+		t.Fatalf("should rate limit: %v", err)
+	}
+	return resp
+}
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		// requests are checked before req, by the same client.
+		requests      int
+		route, ip     string
+		wantCode      rlsv3.RateLimitResponse_Code
+		wantRemaining uint32
+	}{
+		{name: "within limit", route: "api", ip: "203.0.113.1", wantCode: rlsv3.RateLimitResponse_OK, wantRemaining: 1},
+		{name: "over limit", requests: 2, route: "api", ip: "203.0.113.1", wantCode: rlsv3.RateLimitResponse_OVER_LIMIT},
+		{name: "denied client", route: "api", ip: "192.0.2.7", wantCode: rlsv3.RateLimitResponse_OVER_LIMIT},
+		{name: "unknown route", route: "missing", ip: "203.0.113.1", wantCode: rlsv3.RateLimitResponse_OVER_LIMIT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRateLimitClient(t)
+			for i := 0; i < tt.requests; i++ {
+				shouldRateLimit(t, client, rateLimitRequest(tt.route, tt.ip, nil))
+			}
+
+			resp := shouldRateLimit(t, client, rateLimitRequest(tt.route, tt.ip, nil))
+			if resp.GetOverallCode() != tt.wantCode {
+				t.Fatalf("overall code %v, want %v", resp.GetOverallCode(), tt.wantCode)
+			}
+			if got := resp.GetStatuses()[0].GetCode(); got != tt.wantCode {
+				t.Errorf("descriptor code %v, want %v", got, tt.wantCode)
+			}
+			if got := resp.GetStatuses()[0].GetLimitRemaining(); got != tt.wantRemaining {
+				t.Errorf("limit remaining %d, want %d", got, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestRateLimitZeroHitsAddendConsumesNothing(t *testing.T) {
+	client := newRateLimitClient(t)
+
+	for i := 0; i < 3; i++ {
+		resp := shouldRateLimit(t, client, rateLimitRequest("api", "203.0.113.1", wrapperspb.UInt64(0)))
+		if resp.GetOverallCode() != rlsv3.RateLimitResponse_OK {
+			t.Fatalf("check %d: overall code %v, want OK", i, resp.GetOverallCode())
+		}
+	}
+
+	// The limit is exhausted by a hit costing the route maximum, after which a
+	// check reports the descriptor over limit
+	shouldRateLimit(t, client, rateLimitRequest("api", "203.0.113.1", wrapperspb.UInt64(2)))
+	resp := shouldRateLimit(t, client, rateLimitRequest("api", "203.0.113.1", wrapperspb.UInt64(0)))
+	if resp.GetOverallCode() != rlsv3.RateLimitResponse_OVER_LIMIT {
+		t.Fatalf("overall code %v after exhausting the limit, want OVER_LIMIT", resp.GetOverallCode())
+	}
+}
+
+func TestRateLimitClampsHitsAddend(t *testing.T) {
+	client := newRateLimitClient(t)
+
+	// Above the maximum cost of 2, the hit consumes 2 and fits the limit
+	resp := shouldRateLimit(t, client, rateLimitRequest("api", "203.0.113.1", wrapperspb.UInt64(1000)))
+	if resp.GetOverallCode() != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("overall code %v, want OK", resp.GetOverallCode())
+	}
+	if got := resp.GetStatuses()[0].GetLimitRemaining(); got != 0 {
+		t.Errorf("limit remaining %d, want 0", got)
+	}
+}
+
+func TestRateLimitDescriptorKeysDoNotCollide(t *testing.T) {
+	client := newRateLimitClient(t)
+
+	// A descriptor value naming the IP of another client, or a key extracted
+	// from requests, is limited apart from that client
+	for _, value := range []string{"203.0.113.1", "header:abc"} {
+		req := rateLimitRequest("api", "203.0.113.9", nil)
+		req.Descriptors[0].Entries = append(req.Descriptors[0].Entries,
+			&ratelimitv3.RateLimitDescriptor_Entry{Key: "api_key", Value: value})
+		for i := 0; i < 2; i++ {
+			shouldRateLimit(t, client, req)
+		}
+		if resp := shouldRateLimit(t, client, req); resp.GetOverallCode() != rlsv3.RateLimitResponse_OVER_LIMIT {
+			t.Fatalf("%s: overall code %v after exhausting the limit, want OVER_LIMIT", value, resp.GetOverallCode())
+		}
+	}
+
+	resp := shouldRateLimit(t, client, rateLimitRequest("api", "203.0.113.1", nil))
+	if resp.GetOverallCode() != rlsv3.RateLimitResponse_OK {
+		t.Fatalf("overall code %v for the IP client, want OK", resp.GetOverallCode())
+	}
+	if got := resp.GetStatuses()[0].GetLimitRemaining(); got != 1 {
+		t.Errorf("limit remaining %d for the IP client, want 1", got)
+	}
+}
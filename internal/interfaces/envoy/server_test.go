@@ -0,0 +1,20 @@
+package envoy_test
+
+// The services are tested through a gRPC connection to an in-process server,
+// deciding requests against routes kept in memory.
+
+// routesJSON limits the /api/ prefix to 2 requests a minute, rejects unknown
+// routes and denies 192.0.2.0/24.
+const routesJSON = `{
+  "unknown_route": "reject",
+  "access": { "deny": ["192.0.2.0/24"] },
+  "routes": {
+    "api": {
+      "algorithm": "fixed_window",
+      "limit": 2,
+      "window": 60,
+      "match": { "prefix": "/api/" },
+      "cost": { "max": 2 }
+    }
+  }
+}`
@@ -0,0 +1,39 @@
+package grpcauth
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor("secret")
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want codes.Code
+	}{
+		{name: "valid token", md: metadata.Pairs("authorization", "Bearer secret"), want: codes.OK},
+		{name: "wrong token", md: metadata.Pairs("authorization", "Bearer other"), want: codes.Unauthenticated},
+		{name: "not a bearer token", md: metadata.Pairs("authorization", "secret"), want: codes.Unauthenticated},
+		{name: "no metadata", want: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test/Method"}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("code %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/limiter"
+)
+
+const redactedSecret = "[redacted]"
+
+// Prefixes of the keys the admin API may inspect and reset
+const (
+	limiterKeyPrefix = "rl:"
+	penaltyKeyPrefix = "pb:"
+)
+
+type keyStateView struct {
+	Key        string             `json:"key"`
+	Type       string             `json:"type"`
+	TTLSeconds float64            `json:"ttl_seconds"`
+	Value      string             `json:"value,omitempty"`
+	Fields     map[string]string  `json:"fields,omitempty"`
+	Members    []scoredMemberView `json:"members,omitempty"`
+}
+
+type scoredMemberView struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+type banView struct {
+	Route            string  `json:"route"`
+	Client           string  `json:"client"`
+	RemainingSeconds float64 `json:"remaining_seconds"`
+	Strike           int     `json:"strike"`
+}
+
+type circuitBreakerView struct {
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	Override string `json:"override,omitempty"`
+}
+
+// AdminHandler serves the admin API for runtime inspection and management.
+// Every request must carry the admin token as a bearer token.
+type AdminHandler struct {
+	ConfigService  ports.ConfigService
+	LimiterService *service.LimiterService
+	Registry       *limiter.Registry
+	Store          ports.LimiterScore
+	PenaltyBox     ports.PenaltyBox
+	// CircuitBreaker is nil when the limiter store has none.
+	CircuitBreaker ports.CircuitBreakerControl
+	Logger         ports.Logger
+	mux            *http.ServeMux
+	handler        http.Handler
+}
+
+func NewAdminHandler(
+	configService ports.ConfigService,
+	limiterService *service.LimiterService,
+	registry *limiter.Registry,
+	store ports.LimiterScore,
+	penaltyBox ports.PenaltyBox,
+	circuitBreaker ports.CircuitBreakerControl,
+	log ports.Logger,
+	token string,
+) *AdminHandler {
+	h := &AdminHandler{
+		ConfigService:  configService,
+		LimiterService: limiterService,
+		Registry:       registry,
+		Store:          store,
+		PenaltyBox:     penaltyBox,
+		CircuitBreaker: circuitBreaker,
+		Logger:         log,
+		mux:            http.NewServeMux(),
+	}
+	h.handler = RequireToken(token, log, h.mux)
+
+	h.mux.HandleFunc("GET /admin/config", h.getConfig)
+	h.mux.HandleFunc("GET /admin/algorithms", h.getAlgorithms)
+	h.mux.HandleFunc("GET /admin/keys", h.getKeys)
+	h.mux.HandleFunc("DELETE /admin/keys", h.deleteKeys)
+	h.mux.HandleFunc("GET /admin/bans", h.getBans)
+	h.mux.HandleFunc("DELETE /admin/bans", h.deleteBan)
+	h.mux.HandleFunc("GET /admin/circuit-breaker", h.getCircuitBreaker)
+	h.mux.HandleFunc("PUT /admin/circuit-breaker", h.putCircuitBreaker)
+	return h
+}
+
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+// RequireToken serves next to requests carrying token as a bearer token,
+// answering the others with 401. Every request is refused when token is empty.
+func RequireToken(token string, log ports.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r, token) {
+			log.Info("request unauthorized",
+				ports.Field{Key: "remote addr", Val: r.RemoteAddr},
+				ports.Field{Key: "path", Val: r.URL.Path},
+			)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func authorized(r *http.Request, token string) bool {
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// getConfig returns the effective rate limiting config, without JWT secrets.
+func (h *AdminHandler) getConfig(w http.ResponseWriter, _ *http.Request) {
+	cfg := h.ConfigService.GetConfig()
+
+	routes := make(map[string]config.RouteConfig, len(cfg.Routes))
+	for name, route := range cfg.Routes {
+		routes[name] = redactRoute(route)
+	}
+	cfg.Routes = routes
+	if cfg.Default != nil {
+		defaultRoute := redactRoute(*cfg.Default)
+		cfg.Default = &defaultRoute
+	}
+
+	h.writeJSON(w, http.StatusOK, cfg)
+}
+
+func (h *AdminHandler) getAlgorithms(w http.ResponseWriter, _ *http.Request) {
+	algorithms := h.Registry.GetRegisteredAlgorithms()
+	sort.Strings(algorithms)
+	h.writeJSON(w, http.StatusOK, map[string][]string{"algorithms": algorithms})
+}
+
+// getKeys returns the state of a single key, or of every key of a route,
+// optionally restricted to a client.
+func (h *AdminHandler) getKeys(w http.ResponseWriter, r *http.Request) {
+	keys, ok := h.selectKeys(w, r)
+	if !ok {
+		return
+	}
+
+	states := make([]keyStateView, 0, len(keys))
+	for _, key := range keys {
+		state, err := h.Store.Inspect(r.Context(), key)
+		if err != nil {
+			h.storeError(w, "inspect key", err)
+			return
+		}
+		if state.Type != ports.KeyTypeNone || len(keys) == 1 {
+			states = append(states, newKeyStateView(state))
+		}
+	}
+	h.writeJSON(w, http.StatusOK, map[string][]keyStateView{"keys": states})
+}
+
+// deleteKeys resets a single key, or every key of a route, optionally
+// restricted to a client.
+func (h *AdminHandler) deleteKeys(w http.ResponseWriter, r *http.Request) {
+	keys, ok := h.selectKeys(w, r)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.Store.Delete(r.Context(), keys...)
+	if err != nil {
+		h.storeError(w, "delete keys", err)
+		return
+	}
+
+	h.Logger.Info("admin reset rate limit keys",
+		ports.Field{Key: "key", Val: r.URL.Query().Get("key")},
+		ports.Field{Key: "route", Val: r.URL.Query().Get("route")},
+		ports.Field{Key: "client", Val: r.URL.Query().Get("client")},
+		ports.Field{Key: "deleted", Val: deleted},
+	)
+	h.writeJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+// selectKeys resolves the keys selected by the key, or route and client, query
+// parameters. Single keys must be limiter or penalty keys, so the admin token
+// doesn't give access to other data sharing the store.
+func (h *AdminHandler) selectKeys(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	query := r.URL.Query()
+	if key := query.Get("key"); key != "" {
+		if !strings.HasPrefix(key, limiterKeyPrefix) && !strings.HasPrefix(key, penaltyKeyPrefix) {
+			http.Error(w, "key must be a limiter (rl:) or penalty (pb:) key", http.StatusBadRequest)
+			return nil, false
+		}
+		return []string{key}, true
+	}
+
+	route := query.Get("route")
+	if route == "" {
+		http.Error(w, "key or route query parameter required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, pattern := range h.LimiterService.KeyPatterns(route, query.Get("client")) {
+		matched, err := h.Store.Keys(r.Context(), pattern)
+		if err != nil {
+			h.storeError(w, "list keys", err)
+			return nil, false
+		}
+		for _, key := range matched {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, true
+}
+
+// getBans lists the clients banned on a route, or on every route with a penalty.
+func (h *AdminHandler) getBans(w http.ResponseWriter, r *http.Request) {
+	routes := []string{r.URL.Query().Get("route")}
+	if routes[0] == "" {
+		routes = penaltyRoutes(h.ConfigService.GetConfig())
+	}
+
+	bans := make([]banView, 0)
+	for _, route := range routes {
+		routeBans, err := h.PenaltyBox.List(r.Context(), route)
+		if err != nil {
+			h.storeError(w, "list bans", err)
+			return
+		}
+		for _, ban := range routeBans {
+			bans = append(bans, banView{
+				Route:            ban.Route,
+				Client:           ban.ClientKey,
+				RemainingSeconds: ban.Remaining.Seconds(),
+				Strike:           ban.Strike,
+			})
+		}
+	}
+	h.writeJSON(w, http.StatusOK, map[string][]banView{"bans": bans})
+}
+
+// deleteBan lifts the ban of a client on a route.
+func (h *AdminHandler) deleteBan(w http.ResponseWriter, r *http.Request) {
+	route, client := r.URL.Query().Get("route"), r.URL.Query().Get("client")
+	if route == "" || client == "" {
+		http.Error(w, "route and client query parameters required", http.StatusBadRequest)
+		return
+	}
+
+	client = h.LimiterService.AggregateIP(route, client)
+	removed, err := h.PenaltyBox.Remove(r.Context(), route, client)
+	if err != nil {
+		h.storeError(w, "remove ban", err)
+		return
+	}
+
+	h.Logger.Info("admin lifted ban",
+		ports.Field{Key: "event", Val: "client_unbanned"},
+		ports.Field{Key: "route", Val: route},
+		ports.Field{Key: "client", Val: client},
+		ports.Field{Key: "removed", Val: removed},
+	)
+	h.writeJSON(w, http.StatusOK, map[string]bool{"removed": removed})
+}
+
+func (h *AdminHandler) getCircuitBreaker(w http.ResponseWriter, _ *http.Request) {
+	if h.CircuitBreaker == nil {
+		http.Error(w, "limiter store has no circuit breaker", http.StatusNotFound)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, circuitBreakerView(h.CircuitBreaker.Status()))
+}
+
+// putCircuitBreaker overrides the circuit breaker state with {"override": "open"},
+// {"override": "closed"}, or clears the override with {"override": ""}.
+func (h *AdminHandler) putCircuitBreaker(w http.ResponseWriter, r *http.Request) {
+	if h.CircuitBreaker == nil {
+		http.Error(w, "limiter store has no circuit breaker", http.StatusNotFound)
+		return
+	}
+
+	var body struct {
+		Override string `json:"override"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := h.CircuitBreaker.SetOverride(body.Override); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.Logger.Info("admin overrode circuit breaker", ports.Field{Key: "override", Val: body.Override})
+	h.writeJSON(w, http.StatusOK, circuitBreakerView(h.CircuitBreaker.Status()))
+}
+
+func (h *AdminHandler) storeError(w http.ResponseWriter, operation string, err error) {
+	h.Logger.Error("admin store operation failed",
+		ports.Field{Key: "operation", Val: operation},
+		ports.Field{Key: "err", Val: err},
+	)
+	http.Error(w, "limiter store unavailable", http.StatusServiceUnavailable)
+}
+
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		h.Logger.Error("failed to write admin response", ports.Field{Key: "err", Val: err})
+	}
+}
+
+// penaltyRoutes returns the routes with a penalty, including the default route.
+func penaltyRoutes(cfg config.Config) []string {
+	routes := make([]string, 0)
+	for name, route := range cfg.Routes {
+		if route.Penalty.Enabled() {
+			routes = append(routes, name)
+		}
+	}
+	if cfg.Default != nil && cfg.Default.Penalty.Enabled() {
+		routes = append(routes, config.DefaultRoute)
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+func redactRoute(route config.RouteConfig) config.RouteConfig {
+	if route.Key.JWT.Secret != "" {
+		route.Key.JWT.Secret = redactedSecret
+	}
+	return route
+}
+
+func newKeyStateView(state ports.KeyState) keyStateView {
+	view := keyStateView{
+		Key:        state.Key,
+		Type:       state.Type,
+		TTLSeconds: state.TTL.Seconds(),
+		Value:      state.Value,
+		Fields:     state.Fields,
+	}
+	if state.TTL < 0 {
+		view.TTLSeconds = -1
+	}
+	for _, member := range state.Members {
+		view.Members = append(view.Members, scoredMemberView{Member: member.Member, Score: member.Score})
+	}
+	return view
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// Client IP headers with a format of their own. Any other header configured is
+// read as a single address set by the proxy, as X-Real-IP is.
+const (
+	headerForwarded     = "Forwarded"
+	headerXForwardedFor = "X-Forwarded-For"
+	headerXRealIP       = "X-Real-IP"
+)
+
+// ClientIPResolver finds the IP of the client behind the proxies in front of the
+// rate limiter. Client IP headers are believed only when the request comes from
+// a trusted proxy, and list headers are walked right to left, skipping trusted
+// proxies, so clients can't spoof their IP by sending the headers themselves.
+type ClientIPResolver struct {
+	trustedProxies config.IPRanges
+	headers        []string
+}
+
+// NewClientIPResolver creates a resolver believing the headers of trustedProxies,
+// first of headers present, X-Forwarded-For by default. Only list headers the
+// trusted proxies set or overwrite: a header they pass through from the client
+// is spoofable.
+func NewClientIPResolver(trustedProxies config.IPRanges, headers []string) (*ClientIPResolver, error) {
+	if len(headers) == 0 {
+		headers = []string{headerXForwardedFor}
+	}
+
+	canonical := make([]string, 0, len(headers))
+	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			return nil, fmt.Errorf("empty client IP header")
+		}
+		canonical = append(canonical, http.CanonicalHeaderKey(header))
+	}
+
+	return &ClientIPResolver{
+		trustedProxies: trustedProxies,
+		headers:        canonical,
+	}, nil
+}
+
+// ClientIP returns the IP of the client of r.
+func (c *ClientIPResolver) ClientIP(r *http.Request) string {
+	peer := remoteIP(r)
+	addr, ok := parseIP(peer)
+	if !ok || !c.trustedProxies.ContainsAddr(addr) {
+		return peer
+	}
+
+	for _, header := range c.headers {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			continue
+		}
+
+		switch header {
+		case headerForwarded:
+			return c.walk(forwardedFor(values), addr).String()
+		case headerXForwardedFor:
+			return c.walk(splitList(values), addr).String()
+		default:
+			if ip, ok := parseIP(values[0]); ok {
+				return ip.String()
+			}
+		}
+	}
+	return addr.String()
+}
+
+// FromTrustedProxy reports whether the peer of r is a trusted proxy.
+func (c *ClientIPResolver) FromTrustedProxy(r *http.Request) bool {
+	addr, ok := parseIP(remoteIP(r))
+	return ok && c.trustedProxies.ContainsAddr(addr)
+}
+
+// walk returns the rightmost hop that isn't a trusted proxy, or the leftmost hop
+// when every hop is trusted. A hop that isn't an IP ends the walk at the trusted
+// proxy that reported it, since only the client could have forged it.
+func (c *ClientIPResolver) walk(hops []string, peer netip.Addr) netip.Addr {
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseIP(hops[i])
+		if !ok {
+			return client
+		}
+		client = ip
+		if !c.trustedProxies.ContainsAddr(ip) {
+			return client
+		}
+	}
+	return client
+}
+
+// remoteIP returns the IP of the peer of r, the last proxy if any.
+func remoteIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// parseIP parses an IP optionally followed by a port, IPv6 addresses with a
+// port or from the Forwarded header being enclosed in brackets.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap().WithZone(""), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// splitList returns the elements of comma separated header values.
+func splitList(values []string) []string {
+	var elements []string
+	for _, value := range values {
+		elements = append(elements, strings.Split(value, ",")...)
+	}
+	return elements
+}
+
+// forwardedFor returns the for parameters of the Forwarded header (RFC 7239),
+// such as for=192.0.2.60 or for="[2001:db8:cafe::17]:4711". Elements without
+// one are kept as empty hops, being proxies that don't disclose the client.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, element := range splitList(values) {
+		hop := ""
+		for _, pair := range strings.Split(element, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(name, "for") {
+				hop = strings.Trim(value, `"`)
+			}
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+func newResolver(t *testing.T, trusted []string, headers ...string) *ClientIPResolver {
+	t.Helper()
+	ranges, err := config.ParseIPRanges(trusted)
+	if err != nil {
+		t.Fatalf("trusted proxies: %v", err)
+	}
+	resolver, err := NewClientIPResolver(ranges, headers)
+	if err != nil {
+		t.Fatalf("client IP resolver: %v", err)
+	}
+	return resolver
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8:ffff::/48"}
+
+	tests := []struct {
+		name    string
+		headers []string
+		peer    string
+		header  http.Header
+		want    string
+	}{
+		{
+			name:   "untrusted peer spoofing X-Forwarded-For",
+			peer:   "203.0.113.9:4000",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1"}},
+			want:   "203.0.113.9",
+		},
+		{
+			name: "trusted peer without headers",
+			peer: "10.0.0.1:4000",
+			want: "10.0.0.1",
+		},
+		{
+			name:   "rightmost untrusted hop",
+			peer:   "10.0.0.1:4000",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1, 203.0.113.5", "10.0.0.2"}},
+			want:   "203.0.113.5",
+		},
+		{
+			name:   "every hop trusted",
+			peer:   "10.0.0.1:4000",
+			header: http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			want:   "10.0.0.3",
+		},
+		{
+			name:   "hop that isn't an IP",
+			peer:   "10.0.0.1:4000",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1, unknown, 10.0.0.2"}},
+			want:   "10.0.0.2",
+		},
+		{
+			name:   "IPv4-mapped hop with a port",
+			peer:   "10.0.0.1:4000",
+			header: http.Header{"X-Forwarded-For": {"[::ffff:203.0.113.5]:5000"}},
+			want:   "203.0.113.5",
+		},
+		{
+			name:    "Forwarded",
+			headers: []string{"Forwarded"},
+			peer:    "[2001:db8:ffff::1]:4000",
+			header:  http.Header{"Forwarded": {`for=198.51.100.1, for="[2001:db8:cafe::17]:4711";proto=https`}},
+			want:    "2001:db8:cafe::17",
+		},
+		{
+			name:    "Forwarded hop hiding the client",
+			headers: []string{"Forwarded"},
+			peer:    "10.0.0.1:4000",
+			header:  http.Header{"Forwarded": {"for=198.51.100.1, by=10.0.0.2"}},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "single address header",
+			headers: []string{"x-real-ip"},
+			peer:    "10.0.0.1:4000",
+			header:  http.Header{"X-Real-Ip": {"203.0.113.5"}},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "first header present",
+			headers: []string{"X-Real-IP", "X-Forwarded-For"},
+			peer:    "10.0.0.1:4000",
+			header:  http.Header{"X-Forwarded-For": {"203.0.113.5"}},
+			want:    "203.0.113.5",
+		},
+		{
+			name:    "single address header that isn't an IP",
+			headers: []string{"X-Real-IP"},
+			peer:    "10.0.0.1:4000",
+			header:  http.Header{"X-Real-Ip": {"unknown"}},
+			want:    "10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.peer
+			r.Header = tt.header
+
+			if got := newResolver(t, trusted, tt.headers...).ClientIP(r); got != tt.want {
+				t.Errorf("client IP %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromTrustedProxy(t *testing.T) {
+	resolver := newResolver(t, []string{"10.0.0.0/8"})
+
+	for peer, want := range map[string]bool{
+		"10.1.2.3:4000":    true,
+		"203.0.113.9:4000": false,
+		"not an address":   false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = peer
+		if got := resolver.FromTrustedProxy(r); got != want {
+			t.Errorf("peer %q: from trusted proxy %v, want %v", peer, got, want)
+		}
+	}
+}
+
+func TestNewClientIPResolverRejectsEmptyHeader(t *testing.T) {
+	if _, err := NewClientIPResolver(nil, []string{"X-Real-IP", " "}); err == nil {
+		t.Error("empty client IP header accepted")
+	}
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// DecisionHandler answers whether requests are allowed without proxying them,
+// for nginx auth_request, Traefik forwardAuth and Caddy forward_auth: allowed
+// requests get 204 No Content and rejected ones their rejection response, both
+// with the rate limit headers. The request being decided is described by the
+// forwarded headers of the proxy.
+type DecisionHandler struct {
+	handler    *HTTPHandler
+	denyStatus int
+}
+
+// NewDecisionHandler creates a decision handler running the checks of handler.
+// Rate limited requests are answered with denyStatus instead of 429 when it is
+// set, as nginx auth_request only passes 401 and 403 on to clients.
+func NewDecisionHandler(handler *HTTPHandler, denyStatus int) *DecisionHandler {
+	return &DecisionHandler{handler: handler, denyStatus: denyStatus}
+}
+
+func (d *DecisionHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	h := d.handler
+	r = h.originalRequest(r)
+
+	ctx := h.Tracer.Extract(r.Context(), r.Header)
+	ctx, span := h.Tracer.Start(ctx, "DecisionHandler.ServeHTTP", ports.SpanKindServer,
+		ports.Field{Key: "http.request.method", Val: r.Method},
+		ports.Field{Key: "url.path", Val: r.URL.Path})
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+	defer func() {
+		span.SetAttributes(ports.Field{Key: "http.response.status_code", Val: w.status})
+	}()
+
+	var checked http.ResponseWriter = w
+	if d.denyStatus != 0 {
+		checked = &denyStatusWriter{ResponseWriter: w, status: d.denyStatus}
+	}
+
+	h.Logger.Info("deciding request", ports.Field{Key: "url", Val: r.URL.String()}, ports.Field{Key: "method", Val: r.Method})
+	info, key, ok := h.check(checked, r, span)
+	if !ok {
+		return
+	}
+
+	// The proxied request isn't seen completing, so in-flight slots are freed
+	// right away: concurrency limits only apply in proxy mode.
+	if info.Lease != nil {
+		h.releaseLease(r, info.Lease, key)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// originalRequest returns the request described by the forwarded headers of a
+// trusted proxy: X-Forwarded-Method, X-Forwarded-Host and X-Forwarded-Uri set
+// by Traefik and Caddy, or X-Original-Method and X-Original-URI set in the
+// nginx auth_request location. Requests from other peers are decided as is.
+func (h *HTTPHandler) originalRequest(r *http.Request) *http.Request {
+	if !h.ClientIP.FromTrustedProxy(r) {
+		return r
+	}
+
+	original := r.Clone(r.Context())
+	if method := firstHeader(r.Header, "X-Forwarded-Method", "X-Original-Method"); method != "" {
+		original.Method = method
+	}
+	if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+		original.Host = host
+	}
+	if uri := firstHeader(r.Header, "X-Forwarded-Uri", "X-Original-URI"); uri != "" {
+		if parsed, err := url.ParseRequestURI(uri); err == nil {
+			original.URL = parsed
+			original.RequestURI = uri
+		}
+	}
+	return original
+}
+
+func firstHeader(header http.Header, names ...string) string {
+	for _, name := range names {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// denyStatusWriter replaces the 429 status of rate limited requests.
+type denyStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (d *denyStatusWriter) WriteHeader(status int) {
+	if status == http.StatusTooManyRequests {
+		status = d.status
+	}
+	d.ResponseWriter.WriteHeader(status)
+}
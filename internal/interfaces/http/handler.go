@@ -1,119 +1,327 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
-	"strings"
+	"sync"
+	"time"
 
 	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
 	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
 )
 
-// HTTPHandler applies rate limiting before reverse proxying requests to backend Nginx.
+// HTTPHandler applies rate limiting before reverse proxying requests to the
+// upstream pool of their route, or to backend Nginx for routes without one.
 type HTTPHandler struct {
 	LimiterService *service.LimiterService
 	Logger         ports.Logger
 	Proxy          *httputil.ReverseProxy
 	BackendURL     *url.URL
+	Metrics        ports.Metrics
+	Tracer         ports.Tracer
+	ClientIP       *ClientIPResolver
+	Headers        RateLimitHeaders
+	Upstreams      ports.Upstreams
+	// Route, ClientKey and Reject replace how requests are routed, keyed and
+	// rejected when set, for embedding applications.
+	Route     RouteFunc
+	ClientKey KeyFunc
+	Reject    RejectFunc
+	// upstreamProxies caches the reverse proxy of every upstream target, by URL.
+	upstreamProxies sync.Map
 }
 
-func NewHTTPHandler(limiter *service.LimiterService, log ports.Logger, backend string) (*HTTPHandler, error) {
+func NewHTTPHandler(limiter *service.LimiterService, log ports.Logger, metrics ports.Metrics, tracer ports.Tracer, clientIP *ClientIPResolver, headers RateLimitHeaders, upstreams ports.Upstreams, backend string) (*HTTPHandler, error) {
 	parsedURL, err := url.Parse(backend)
 	if err != nil {
 		return nil, err
 	}
 
-	proxy := httputil.NewSingleHostReverseProxy(parsedURL)
+	return &HTTPHandler{
+		LimiterService: limiter,
+		Logger:         log,
+		Proxy:          newReverseProxy(parsedURL, log, tracer),
+		BackendURL:     parsedURL,
+		Metrics:        metrics,
+		Tracer:         tracer,
+		ClientIP:       clientIP,
+		Headers:        headers,
+		Upstreams:      upstreams,
+	}, nil
+}
+
+// newReverseProxy creates a reverse proxy to target, keeping the path and
+// query of requests and propagating their trace context.
+func newReverseProxy(target *url.URL, log ports.Logger, tracer ports.Tracer) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	originalDirector := proxy.Director
 
 	// Customize director to preserve forwarded headers
 	proxy.Director = func(req *http.Request) {
 		log.Info("director called", ports.Field{Key: "url", Val: req.URL.String()})
 
-		// Preserve original path instead of letting originalDirector override it
-		req.URL.Scheme = parsedURL.Scheme
-		req.URL.Host = parsedURL.Host
-		req.URL.Path = req.URL.Path // <- this keeps the original path
-		req.URL.RawQuery = req.URL.RawQuery
-		req.Host = parsedURL.Host
+		if target.Path != "" && target.Path != "/" {
+			// Prefix the path of the request with the path of the target
+			originalDirector(req)
+		}
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		req.Host = target.Host
 
 		// Preserve client's IP chain
 		if xfwd := req.Header.Get("X-Forwarded-For"); xfwd == "" {
-			req.Header.Set("X-Forwarded-For", getClientIP(req))
+			req.Header.Set("X-Forwarded-For", remoteIP(req))
 		}
 
 		// Optional debug
 		req.Header.Set("X-Rate-Limiter", "checked")
-	}
 
-	return &HTTPHandler{
-		LimiterService: limiter,
-		Logger:         log,
-		Proxy:          proxy,
-		BackendURL:     parsedURL,
-	}, nil
+		// Propagate the trace context of the proxy span to the backend
+		tracer.Inject(req.Context(), req.Header)
+	}
+	return proxy
 }
 
-func (h *HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+func (h *HTTPHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	ctx := h.Tracer.Extract(r.Context(), r.Header)
+	ctx, span := h.Tracer.Start(ctx, "HTTPHandler.ServeHTTP", ports.SpanKindServer,
+		ports.Field{Key: "http.request.method", Val: r.Method},
+		ports.Field{Key: "url.path", Val: r.URL.Path})
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+	defer func() {
+		span.SetAttributes(ports.Field{Key: "http.response.status_code", Val: w.status})
+	}()
+
 	h.Logger.Info("proxying request", ports.Field{Key: "url", Val: r.URL.String()}, ports.Field{Key: "method", Val: r.Method})
-	clientIP := getClientIP(r)
-	key := r.Header.Get("X-Rate-Limit-Rule")
-	h.Logger.Info("checking rate limit", ports.Field{Key: "ip", Val: clientIP}, ports.Field{Key: "route", Val: key})
+	info, key, ok := h.check(w, r, span)
+	if !ok {
+		return
+	}
+
+	h.Logger.Info("proxying allowed request",
+		ports.Field{Key: "ip", Val: h.ClientIP.ClientIP(r)},
+		ports.Field{Key: "route", Val: key},
+	)
+
+	if info.Lease != nil {
+		defer h.releaseLease(r, info.Lease, key)
+	}
+
+	h.proxy(w, r, key)
+}
+
+// check runs the rate limits of the route of r. Rejected requests are answered
+// and false is returned; allowed requests are left to the caller along with
+// their route, after the rate limit headers have been set.
+func (h *HTTPHandler) check(w http.ResponseWriter, r *http.Request, span ports.Span) (ports.RateLimitInfo, string, bool) {
+	clientIP := h.ClientIP.ClientIP(r)
+	key := h.resolveRoute(r)
+	span.SetAttributes(ports.Field{Key: "rate_limiter.route", Val: key})
+	routeConfig, _ := h.LimiterService.Route(key)
+	cost := requestCost(r, routeConfig)
+
+	clientKey, err := h.clientKey(r, routeConfig, clientIP)
+	if err != nil {
+		h.Logger.Info("rate limit key missing",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "path", Val: r.URL.Path},
+			ports.Field{Key: "err", Val: err.Error()},
+		)
+		http.Error(w, errMissingClientKey.Error(), http.StatusUnauthorized)
+		return ports.RateLimitInfo{}, key, false
+	}
+	h.Logger.Info("checking rate limit", ports.Field{Key: "ip", Val: clientIP}, ports.Field{Key: "route", Val: key}, ports.Field{Key: "client key", Val: clientKey}, ports.Field{Key: "cost", Val: cost})
 
-	info, err := h.LimiterService.AllowWithInfo(r.Context(), clientIP, key)
+	info, err := h.LimiterService.AllowWithInfo(r.Context(), clientIP, key, clientKey, cost)
 	if err != nil {
 		h.Logger.Error("limiter check failed", ports.Field{Key: "err", Val: err})
+		span.RecordError(err)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+		return ports.RateLimitInfo{}, key, false
+	}
+
+	h.Headers.Write(w.Header(), info, time.Now())
+
+	if !info.Allowed && h.Reject != nil {
+		h.Logger.Info("request rejected",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "client key", Val: clientKey},
+			ports.Field{Key: "method", Val: r.Method},
+			ports.Field{Key: "path", Val: r.URL.Path},
+		)
+		h.Reject(w, r, info)
+		return ports.RateLimitInfo{}, key, false
+	}
+
+	if info.Blocked {
+		h.Logger.Info("client IP denied",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "method", Val: r.Method},
+			ports.Field{Key: "path", Val: r.URL.Path},
+		)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return ports.RateLimitInfo{}, key, false
+	}
+
+	if info.RoutePolicy == config.UnknownRouteReject {
+		h.Logger.Info("unknown route rejected",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "method", Val: r.Method},
+			ports.Field{Key: "path", Val: r.URL.Path},
+		)
+		http.Error(w, "unknown route", http.StatusForbidden)
+		return ports.RateLimitInfo{}, key, false
+	}
+
+	if !info.Allowed && info.FailureMode == config.FailureModeClosed {
+		h.Logger.Info("limiter unavailable, failing closed",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "method", Val: r.Method},
+			ports.Field{Key: "path", Val: r.URL.Path},
+		)
+		http.Error(w, "rate limiter unavailable", http.StatusServiceUnavailable)
+		return ports.RateLimitInfo{}, key, false
 	}
 
-	h.setRateLimitHeaders(w, info)
+	if !info.Allowed && info.Banned {
+		h.Logger.Info("client banned",
+			ports.Field{Key: "ip", Val: clientIP},
+			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "client key", Val: clientKey},
+			ports.Field{Key: "retry after", Val: info.RetryAfter.String()},
+			ports.Field{Key: "method", Val: r.Method},
+			ports.Field{Key: "path", Val: r.URL.Path},
+		)
+		h.reject(w, r, routeConfig.Rejection, info, h.LimiterService.RouteLabel(key), reasonBanned,
+			"too many rejected requests, client temporarily banned")
+		return ports.RateLimitInfo{}, key, false
+	}
 
 	if !info.Allowed {
 		h.Logger.Info("rate limit exceeded",
 			ports.Field{Key: "ip", Val: clientIP},
 			ports.Field{Key: "route key", Val: key},
+			ports.Field{Key: "route policy", Val: info.RoutePolicy},
+			ports.Field{Key: "failure mode", Val: info.FailureMode},
 			ports.Field{Key: "method", Val: r.Method},
 			ports.Field{Key: "path", Val: r.URL.Path},
 		)
-		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
-		return
+		h.reject(w, r, routeConfig.Rejection, info, h.LimiterService.RouteLabel(key), reasonRateLimited, "rate limit exceeded")
+		return ports.RateLimitInfo{}, key, false
 	}
 
 	h.Logger.Info("rate limit not exceeded",
 		ports.Field{Key: "ip", Val: clientIP},
 		ports.Field{Key: "route key", Val: key},
+		ports.Field{Key: "route policy", Val: info.RoutePolicy},
+		ports.Field{Key: "failure mode", Val: info.FailureMode},
 		ports.Field{Key: "method", Val: r.Method},
 		ports.Field{Key: "path", Val: r.URL.Path},
 	)
+	return info, key, true
+}
 
-	h.Logger.Info("proxying allowed request",
-		ports.Field{Key: "ip", Val: clientIP},
-		ports.Field{Key: "route", Val: key},
-	)
+// proxy forwards the request to a target of the upstream pool of the route, or
+// to the backend, within a client span, recording the upstream latency.
+func (h *HTTPHandler) proxy(w *statusRecorder, r *http.Request, route string) {
+	routeConfig, label := h.LimiterService.RouteWithLabel(route)
+
+	proxy, backend := h.Proxy, h.BackendURL
+	if target, done, ok := h.Upstreams.Pick(label, routeConfig.Upstream); ok {
+		defer done()
+		proxy, backend = h.upstreamProxy(target), target
+	}
+
+	ctx, span := h.Tracer.Start(r.Context(), "HTTPHandler.proxy", ports.SpanKindClient,
+		ports.Field{Key: "server.address", Val: backend.Host},
+		ports.Field{Key: "rate_limiter.route", Val: route})
+	defer span.End()
+
+	start := time.Now()
+	proxy.ServeHTTP(w, r.WithContext(ctx))
+	h.Metrics.ObserveUpstreamLatency(label, w.status, time.Since(start))
+
+	span.SetAttributes(ports.Field{Key: "http.response.status_code", Val: w.status})
+	if w.status >= http.StatusInternalServerError {
+		span.RecordError(fmt.Errorf("upstream responded %d", w.status))
+	}
+}
 
-	h.Proxy.ServeHTTP(w, r)
+func (h *HTTPHandler) upstreamProxy(target *url.URL) *httputil.ReverseProxy {
+	if proxy, ok := h.upstreamProxies.Load(target.String()); ok {
+		return proxy.(*httputil.ReverseProxy)
+	}
+	proxy, _ := h.upstreamProxies.LoadOrStore(target.String(), newReverseProxy(target, h.Logger, h.Tracer))
+	return proxy.(*httputil.ReverseProxy)
+}
+
+// ForgetUpstreams drops the cached reverse proxies of targets, once no upstream
+// pool balances over them.
+func (h *HTTPHandler) ForgetUpstreams(targets []*url.URL) {
+	for _, target := range targets {
+		h.upstreamProxies.Delete(target.String())
+	}
 }
 
-func (h *HTTPHandler) setRateLimitHeaders(w http.ResponseWriter, info ports.RateLimitInfo) {
-	if info.Limit > 0 {
-		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", info.Limit))
-		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", info.Remaining))
+// resolveRoute selects the route of the request from the route matchers, falling
+// back to the X-Rate-Limit-Rule header set by a trusted proxy, unless Route is
+// set. The header of other peers is ignored, as clients would pick the most
+// lenient route; their requests go to the unknown route policy.
+func (h *HTTPHandler) resolveRoute(r *http.Request) string {
+	if h.Route != nil {
+		return h.Route(r)
+	}
+	if route, ok := h.LimiterService.MatchRoute(r.Method, r.Host, r.URL.Path); ok {
+		return route
+	}
+	if h.ClientIP.FromTrustedProxy(r) {
+		return r.Header.Get("X-Rate-Limit-Rule")
 	}
-	if info.ResetTime > 0 {
-		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", info.ResetTime))
+	return ""
+}
+
+// clientKey extracts the client key of the request with ClientKey when set,
+// prefixed with config.CallerKeyPrefix, or with the key definition of the route.
+func (h *HTTPHandler) clientKey(r *http.Request, routeConfig config.RouteConfig, clientIP string) (string, error) {
+	if h.ClientKey != nil {
+		key, err := h.ClientKey(r, routeConfig, clientIP)
+		if err != nil || key == "" {
+			return "", err
+		}
+		return config.CallerKeyPrefix + key, nil
 	}
+	return extractClientKey(r, routeConfig.Key, routeConfig.IPPrefix.Apply(clientIP))
 }
 
-func getClientIP(r *http.Request) string {
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		parts := strings.Split(forwarded, ",")
-		return strings.TrimSpace(parts[0])
+// releaseLease frees the in-flight slot once the proxied response has completed.
+// It must outlive the request context, which is already cancelled when clients disconnect.
+func (h *HTTPHandler) releaseLease(r *http.Request, lease ports.Lease, route string) {
+	if err := lease.Release(context.WithoutCancel(r.Context())); err != nil {
+		h.Logger.Error("failed to release concurrency lease",
+			ports.Field{Key: "route", Val: route},
+			ports.Field{Key: "err", Val: err},
+		)
 	}
-	ip := r.RemoteAddr
-	if i := strings.LastIndex(ip, ":"); i != -1 {
-		ip = ip[:i]
+}
+
+// requestCost resolves how many units of the route limit the request consumes.
+// Unknown routes have a zero RouteConfig, which resolves to config.DefaultCost.
+func requestCost(r *http.Request, routeConfig config.RouteConfig) int {
+	var headerValue string
+	if routeConfig.Cost.Header != "" {
+		headerValue = r.Header.Get(routeConfig.Cost.Header)
 	}
-	return ip
+	return routeConfig.Cost.Resolve(r.Method, r.URL.Path, headerValue)
 }
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Register the SHA-2 hashes used by HS* and RS* signatures.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+var (
+	errMalformedJWT = errors.New("malformed jwt")
+	errInvalidJWT   = errors.New("invalid jwt signature")
+	errExpiredJWT   = errors.New("jwt expired or not yet valid")
+)
+
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// rsaKeys caches parsed public keys by their PEM encoding.
+var rsaKeys sync.Map
+
+// parseJWTClaims decodes the claims of token. Claims are only trusted without
+// verification when the route configures neither a secret nor a public key.
+func parseJWTClaims(token string, jwtCfg config.JWTConfig) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errMalformedJWT
+	}
+
+	if jwtCfg.Verified() {
+		if err := verifyJWT(parts, jwtCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errMalformedJWT
+	}
+
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, errMalformedJWT
+	}
+
+	if jwtCfg.Verified() {
+		if err := checkJWTTimes(claims, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return claims, nil
+}
+
+func verifyJWT(parts []string, jwtCfg config.JWTConfig) error {
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return errMalformedJWT
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return errMalformedJWT
+	}
+	// The algorithm is pinned by the route, never chosen by the token.
+	if header.Alg != jwtCfg.Algorithm {
+		return errInvalidJWT
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errMalformedJWT
+	}
+
+	hash, ok := jwtHashes[jwtCfg.Algorithm[2:]]
+	if !ok {
+		return fmt.Errorf("unsupported jwt algorithm %q", jwtCfg.Algorithm)
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	switch {
+	case strings.HasPrefix(jwtCfg.Algorithm, "HS"):
+		mac := hmac.New(hash.New, []byte(jwtCfg.Secret))
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return errInvalidJWT
+		}
+	case strings.HasPrefix(jwtCfg.Algorithm, "RS"):
+		key, err := rsaPublicKey(jwtCfg.PublicKey)
+		if err != nil {
+			return err
+		}
+		hasher := hash.New()
+		hasher.Write(signed)
+		if err := rsa.VerifyPKCS1v15(key, hash, hasher.Sum(nil), signature); err != nil {
+			return errInvalidJWT
+		}
+	default:
+		return fmt.Errorf("unsupported jwt algorithm %q", jwtCfg.Algorithm)
+	}
+	return nil
+}
+
+func rsaPublicKey(pemKey string) (*rsa.PublicKey, error) {
+	if key, ok := rsaKeys.Load(pemKey); ok {
+		return key.(*rsa.PublicKey), nil
+	}
+
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("invalid jwt public key: no PEM block")
+	}
+
+	var key *rsa.PublicKey
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt public key: %w", err)
+		}
+		key = parsed
+	default:
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwt public key: %w", err)
+		}
+		rsaKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, errors.New("invalid jwt public key: not an RSA key")
+		}
+		key = rsaKey
+	}
+
+	rsaKeys.Store(pemKey, key)
+	return key, nil
+}
+
+func checkJWTTimes(claims map[string]interface{}, now time.Time) error {
+	if exp, ok := claimTime(claims, "exp"); ok && !now.Before(exp) {
+		return errExpiredJWT
+	}
+	if nbf, ok := claimTime(claims, "nbf"); ok && now.Before(nbf) {
+		return errExpiredJWT
+	}
+	return nil
+}
+
+func claimTime(claims map[string]interface{}, name string) (time.Time, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// claimString returns a string or numeric claim, or an empty string otherwise.
+func claimString(claims map[string]interface{}, name string) string {
+	switch v := claims[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		return ""
+	}
+}
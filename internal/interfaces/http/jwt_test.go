@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+const jwtSecret = "test-secret"
+
+// signJWT returns a token of claims signed with sign, alg being the algorithm
+// of its header.
+func signJWT(t *testing.T, alg string, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatalf("marshal header: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("marshal claims: %v", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(signed []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(signed)
+		return mac.Sum(nil)
+	}
+}
+
+func TestParseJWTClaimsHS256(t *testing.T) {
+	jwtCfg := config.JWTConfig{Algorithm: "HS256", Secret: jwtSecret}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			token: signJWT(t, "HS256", map[string]interface{}{"sub": "alice", "exp": now + 60}, hs256(jwtSecret)),
+		},
+		{
+			name:    "wrong secret",
+			token:   signJWT(t, "HS256", map[string]interface{}{"sub": "alice"}, hs256("other")),
+			wantErr: errInvalidJWT,
+		},
+		{
+			name:    "algorithm chosen by the token",
+			token:   signJWT(t, "none", map[string]interface{}{"sub": "alice"}, func([]byte) []byte { return nil }),
+			wantErr: errInvalidJWT,
+		},
+		{
+			name:    "expired",
+			token:   signJWT(t, "HS256", map[string]interface{}{"sub": "alice", "exp": now - 60}, hs256(jwtSecret)),
+			wantErr: errExpiredJWT,
+		},
+		{
+			name:    "not yet valid",
+			token:   signJWT(t, "HS256", map[string]interface{}{"sub": "alice", "nbf": now + 60}, hs256(jwtSecret)),
+			wantErr: errExpiredJWT,
+		},
+		{
+			name:    "malformed",
+			token:   "not.a-jwt",
+			wantErr: errMalformedJWT,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := parseJWTClaims(tt.token, jwtCfg)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && claimString(claims, "sub") != "alice" {
+				t.Errorf("sub %q, want alice", claimString(claims, "sub"))
+			}
+		})
+	}
+}
+
+func TestParseJWTClaimsRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	jwtCfg := config.JWTConfig{
+		Algorithm: "RS256",
+		PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}
+	rs256 := func(signed []byte) []byte {
+		digest := sha256.Sum256(signed)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return signature
+	}
+
+	claims, err := parseJWTClaims(signJWT(t, "RS256", map[string]interface{}{"sub": 42}, rs256), jwtCfg)
+	if err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	if got := claimString(claims, "sub"); got != "42" {
+		t.Errorf("numeric sub %q, want 42", got)
+	}
+
+	// An HS256 token signed with the public key must not pass as RS256
+	forged := signJWT(t, "HS256", map[string]interface{}{"sub": "mallory"}, hs256(jwtCfg.PublicKey))
+	if _, err := parseJWTClaims(forged, jwtCfg); !errors.Is(err, errInvalidJWT) {
+		t.Errorf("token signed with the public key: error %v, want %v", err, errInvalidJWT)
+	}
+}
+
+func TestParseJWTClaimsUnverified(t *testing.T) {
+	// Without a secret or public key, claims are read as they are, whatever
+	// the signature and times
+	token := signJWT(t, "none", map[string]interface{}{"sub": "alice", "exp": 1}, func([]byte) []byte { return nil })
+	claims, err := parseJWTClaims(token, config.JWTConfig{})
+	if err != nil {
+		t.Fatalf("unverified token: %v", err)
+	}
+	if got := claimString(claims, "sub"); got != "alice" {
+		t.Errorf("sub %q, want alice", got)
+	}
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+var errMissingClientKey = errors.New("missing rate limit key")
+
+// extractClientKey resolves the route key definition against the request,
+// prefixed with its sources such as "header:" so that no extracted key can take
+// the limits of an IP. It returns an empty key, meaning the client IP, when the
+// route keys by IP or when a source is missing and the route falls back to the IP.
+func extractClientKey(r *http.Request, keyCfg config.KeyConfig, clientIP string) (string, error) {
+	if keyCfg.IsClientIP() {
+		return "", nil
+	}
+
+	var (
+		sb        strings.Builder
+		claims    map[string]interface{}
+		claimsErr error
+		parsed    bool
+	)
+
+	for _, segment := range keyCfg.Segments {
+		var value string
+
+		switch segment.Source {
+		case config.KeySourceLiteral:
+			sb.WriteString(segment.Name)
+			continue
+		case config.KeySourceIP:
+			value = clientIP
+		case config.KeySourceHeader:
+			value = strings.TrimSpace(r.Header.Get(segment.Name))
+		case config.KeySourceQuery:
+			value = r.URL.Query().Get(segment.Name)
+		case config.KeySourceCookie:
+			if cookie, err := r.Cookie(segment.Name); err == nil {
+				value = cookie.Value
+			}
+		case config.KeySourceJWT:
+			if !parsed {
+				claims, claimsErr = parseJWTClaims(bearerToken(r, keyCfg.JWT), keyCfg.JWT)
+				parsed = true
+			}
+			if claimsErr == nil {
+				value = claimString(claims, segment.Name)
+			}
+		}
+
+		if value == "" {
+			if keyCfg.Missing == config.KeyMissingReject {
+				return "", errMissingClientKey
+			}
+			return "", nil
+		}
+		sb.WriteString(value)
+	}
+
+	return keyPrefix(keyCfg.Segments) + sb.String(), nil
+}
+
+// keyPrefix names the sources of a key, e.g. "header:" or "header+jwt:".
+func keyPrefix(segments []config.KeySegment) string {
+	var sources []string
+	for _, segment := range segments {
+		if segment.Source != config.KeySourceLiteral && !slices.Contains(sources, segment.Source) {
+			sources = append(sources, segment.Source)
+		}
+	}
+	return strings.Join(sources, "+") + ":"
+}
+
+// bearerToken returns the JWT from the configured cookie or header, Authorization by default.
+func bearerToken(r *http.Request, jwtCfg config.JWTConfig) string {
+	if jwtCfg.Cookie != "" {
+		if cookie, err := r.Cookie(jwtCfg.Cookie); err == nil {
+			return cookie.Value
+		}
+		return ""
+	}
+
+	header := jwtCfg.Header
+	if header == "" {
+		header = "Authorization"
+	}
+
+	token := strings.TrimSpace(r.Header.Get(header))
+	if len(token) > 7 && strings.EqualFold(token[:7], "bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	return token
+}
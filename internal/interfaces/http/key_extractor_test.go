@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+func keyConfig(t *testing.T, template, missing string) config.KeyConfig {
+	t.Helper()
+	segments, err := config.ParseKeyTemplate(template)
+	if err != nil {
+		t.Fatalf("key template %q: %v", template, err)
+	}
+	return config.KeyConfig{
+		Segments: segments,
+		JWT:      config.JWTConfig{Algorithm: "HS256", Secret: jwtSecret},
+		Missing:  missing,
+	}
+}
+
+func TestExtractClientKey(t *testing.T) {
+	token := signJWT(t, "HS256", map[string]interface{}{"sub": "alice"}, hs256(jwtSecret))
+	forged := signJWT(t, "HS256", map[string]interface{}{"sub": "alice"}, hs256("other"))
+
+	tests := []struct {
+		name     string
+		template string
+		missing  string
+		target   string
+		header   http.Header
+		want     string
+		wantErr  error
+	}{
+		{name: "client IP", template: "ip", want: ""},
+		{
+			name:     "header",
+			template: "header:X-API-Key",
+			header:   http.Header{"X-Api-Key": {" abc "}},
+			want:     "header:abc",
+		},
+		{
+			name:     "header naming an IP",
+			template: "header:X-API-Key",
+			header:   http.Header{"X-Api-Key": {"203.0.113.1"}},
+			want:     "header:203.0.113.1",
+		},
+		{name: "query", template: "query:api_key", target: "/?api_key=abc", want: "query:abc"},
+		{
+			name:     "cookie",
+			template: "cookie:session",
+			header:   http.Header{"Cookie": {"session=abc"}},
+			want:     "cookie:abc",
+		},
+		{
+			name:     "template",
+			template: "{header:X-Tenant}/{jwt:sub}@{ip}",
+			header:   http.Header{"X-Tenant": {"acme"}, "Authorization": {"Bearer " + token}},
+			want:     "header+jwt+ip:acme/alice@203.0.113.1",
+		},
+		{
+			name:     "missing source falling back to the IP",
+			template: "header:X-API-Key",
+			want:     "",
+		},
+		{
+			name:     "missing source rejected",
+			template: "header:X-API-Key",
+			missing:  config.KeyMissingReject,
+			wantErr:  errMissingClientKey,
+		},
+		{
+			name:     "forged JWT rejected",
+			template: "jwt:sub",
+			missing:  config.KeyMissingReject,
+			header:   http.Header{"Authorization": {"Bearer " + forged}},
+			wantErr:  errMissingClientKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+			r := httptest.NewRequest(http.MethodGet, target, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+
+			got, err := extractClientKey(r, keyConfig(t, tt.template, tt.missing), "203.0.113.1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("key %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "bearer  abc ")
+	r.Header.Set("X-Token", "def")
+	r.AddCookie(&http.Cookie{Name: "token", Value: "ghi"})
+
+	for _, tt := range []struct {
+		jwtCfg config.JWTConfig
+		want   string
+	}{
+		{jwtCfg: config.JWTConfig{}, want: "abc"},
+		{jwtCfg: config.JWTConfig{Header: "X-Token"}, want: "def"},
+		{jwtCfg: config.JWTConfig{Cookie: "token"}, want: "ghi"},
+		{jwtCfg: config.JWTConfig{Cookie: "missing"}, want: ""},
+	} {
+		if got := bearerToken(r, tt.jwtCfg); got != tt.want {
+			t.Errorf("%+v: token %q, want %q", tt.jwtCfg, got, tt.want)
+		}
+	}
+}
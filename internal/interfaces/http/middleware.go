@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// RouteFunc returns the route of a request.
+type RouteFunc func(r *http.Request) string
+
+// KeyFunc returns the client key of a request to a route, or an empty key to
+// key the client by IP. An error rejects the request with 401 Unauthorized.
+type KeyFunc func(r *http.Request, route config.RouteConfig, clientIP string) (string, error)
+
+// RejectFunc answers a request denied by the limiter, info telling why: Blocked,
+// Banned, the RoutePolicy of unknown routes, the FailureMode, or the limits.
+type RejectFunc func(w http.ResponseWriter, r *http.Request, info ports.RateLimitInfo)
+
+// Middleware runs the checks of an HTTPHandler in front of another handler,
+// for services embedding the limiter rather than running it as a proxy.
+// Allowed requests are served by next with the rate limit headers set, and
+// hold their in-flight slots until next returns.
+type Middleware struct {
+	handler *HTTPHandler
+	next    http.Handler
+}
+
+// NewMiddleware creates a middleware running the checks of handler before next.
+// Only the limiter, logger, metrics, tracer, client IP and header fields of
+// handler are used.
+func NewMiddleware(handler *HTTPHandler, next http.Handler) *Middleware {
+	return &Middleware{handler: handler, next: next}
+}
+
+func (m *Middleware) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	h := m.handler
+
+	ctx := h.Tracer.Extract(r.Context(), r.Header)
+	ctx, span := h.Tracer.Start(ctx, "Middleware.ServeHTTP", ports.SpanKindServer,
+		ports.Field{Key: "http.request.method", Val: r.Method},
+		ports.Field{Key: "url.path", Val: r.URL.Path})
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	w := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+	defer func() {
+		span.SetAttributes(ports.Field{Key: "http.response.status_code", Val: w.status})
+	}()
+
+	info, key, ok := h.check(w, r, span)
+	if !ok {
+		return
+	}
+
+	if info.Lease != nil {
+		defer h.releaseLease(r, info.Lease, key)
+	}
+	m.next.ServeHTTP(w, r)
+}
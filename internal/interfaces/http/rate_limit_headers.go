@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// Rate limit header styles
+const (
+	// HeaderStyleLegacy emits X-RateLimit-Limit, X-RateLimit-Remaining and
+	// X-RateLimit-Reset, an absolute Unix time.
+	HeaderStyleLegacy = "legacy"
+	// HeaderStyleIETF emits the RateLimit and RateLimit-Policy structured
+	// headers of the IETF draft, with resets in delta seconds.
+	HeaderStyleIETF = "ietf"
+)
+
+// RateLimitHeaders writes the rate limit state of a request to its response in
+// the configured header styles. Retry-After is written on rejections whatever
+// the styles.
+type RateLimitHeaders struct {
+	legacy bool
+	ietf   bool
+}
+
+// NewRateLimitHeaders selects the header styles to emit, HeaderStyleLegacy by default.
+func NewRateLimitHeaders(styles []string) (RateLimitHeaders, error) {
+	if len(styles) == 0 {
+		return RateLimitHeaders{legacy: true}, nil
+	}
+
+	var headers RateLimitHeaders
+	for _, style := range styles {
+		switch strings.ToLower(strings.TrimSpace(style)) {
+		case HeaderStyleLegacy:
+			headers.legacy = true
+		case HeaderStyleIETF:
+			headers.ietf = true
+		default:
+			return RateLimitHeaders{}, fmt.Errorf("rate limit header style must be %q or %q, got %q",
+				HeaderStyleLegacy, HeaderStyleIETF, style)
+		}
+	}
+	return headers, nil
+}
+
+func (h RateLimitHeaders) Write(header http.Header, info ports.RateLimitInfo, now time.Time) {
+	if h.legacy {
+		if info.Limit > 0 {
+			header.Set("X-RateLimit-Limit", strconv.Itoa(info.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(info.Remaining))
+		}
+		if info.ResetTime > 0 {
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(info.ResetTime, 10))
+		}
+	}
+
+	if h.ietf {
+		if policy := rateLimitPolicy(info.Policies); policy != "" {
+			header.Set("RateLimit-Policy", policy)
+		}
+		if info.Limit > 0 && info.Policy != "" {
+			item := fmt.Sprintf("%s;r=%d", quoteString(info.Policy), max(info.Remaining, 0))
+			if info.ResetTime > 0 {
+				item += fmt.Sprintf(";t=%d", max(info.ResetTime-now.Unix(), 0))
+			}
+			header.Set("RateLimit", item)
+		}
+	}
+
+	if info.RoutePolicy != "" {
+		header.Set("X-RateLimit-Route-Policy", info.RoutePolicy)
+	}
+	if info.FailureMode != "" {
+		header.Set("X-RateLimit-Failure-Mode", info.FailureMode)
+	}
+	if !info.Allowed {
+		if retryAfter := retryAfterSeconds(info, now); retryAfter > 0 {
+			header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		}
+	}
+}
+
+// rateLimitPolicy lists the quota and window in seconds of every limit, e.g.
+// "burst";q=3;w=5, "sustained";q=100;w=3600. In-flight limits have no window.
+func rateLimitPolicy(policies []ports.LimitPolicy) string {
+	items := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Quota.Limit <= 0 {
+			continue
+		}
+		item := fmt.Sprintf("%s;q=%d", quoteString(policy.Name), policy.Quota.Limit)
+		if !policy.Quota.InFlight && policy.Quota.Period > 0 {
+			item += fmt.Sprintf(";w=%d", int64(math.Ceil(policy.Quota.Period.Seconds())))
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, ", ")
+}
+
+// retryAfterSeconds returns when a rejected client may retry, in whole seconds
+// rounded up, from the limiter's estimate or else the reset of the limit. It
+// is 0 when unknown.
+func retryAfterSeconds(info ports.RateLimitInfo, now time.Time) int64 {
+	if info.RetryAfter > 0 {
+		return int64(math.Ceil(info.RetryAfter.Seconds()))
+	}
+	if info.ResetTime > 0 {
+		return max(info.ResetTime-now.Unix(), 1)
+	}
+	return 0
+}
+
+// quoteString formats s as a structured field string (RFC 8941).
+func quoteString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			sb.WriteByte('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+func TestRateLimitHeadersWrite(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	allowed := ports.RateLimitInfo{
+		Allowed:   true,
+		Limit:     3,
+		Remaining: 2,
+		ResetTime: now.Unix() + 5,
+		Policy:    "burst",
+		Policies: []ports.LimitPolicy{
+			{Name: "burst", Quota: config.Quota{Limit: 3, Period: 5 * time.Second}},
+			{Name: "sustained", Quota: config.Quota{Limit: 100, Period: 90 * time.Minute}},
+			{Name: "jobs", Quota: config.Quota{Limit: 2, InFlight: true}},
+		},
+	}
+	rejected := allowed
+	rejected.Allowed = false
+	rejected.Remaining = -1
+
+	tests := []struct {
+		name   string
+		styles []string
+		info   ports.RateLimitInfo
+		want   map[string]string
+	}{
+		{
+			name: "legacy by default",
+			info: allowed,
+			want: map[string]string{
+				"X-RateLimit-Limit":     "3",
+				"X-RateLimit-Remaining": "2",
+				"X-RateLimit-Reset":     "1700000005",
+				"RateLimit":             "",
+				"Retry-After":           "",
+			},
+		},
+		{
+			name:   "ietf",
+			styles: []string{" IETF "},
+			info:   allowed,
+			want: map[string]string{
+				"X-RateLimit-Limit": "",
+				"RateLimit":         `"burst";r=2;t=5`,
+				"RateLimit-Policy":  `"burst";q=3;w=5, "sustained";q=100;w=5400, "jobs";q=2`,
+			},
+		},
+		{
+			name:   "rejected",
+			styles: []string{HeaderStyleLegacy, HeaderStyleIETF},
+			info:   rejected,
+			want: map[string]string{
+				"X-RateLimit-Remaining": "-1",
+				"RateLimit":             `"burst";r=0;t=5`,
+				"Retry-After":           "5",
+			},
+		},
+		{
+			name: "rejected with a retry estimate",
+			info: ports.RateLimitInfo{Limit: 10, ResetTime: now.Unix() + 60, RetryAfter: 1500 * time.Millisecond},
+			want: map[string]string{"Retry-After": "2"},
+		},
+		{
+			name: "decided by a policy",
+			info: ports.RateLimitInfo{Allowed: true, Limit: -1, Remaining: -1, RoutePolicy: "default", FailureMode: "open"},
+			want: map[string]string{
+				"X-RateLimit-Limit":        "",
+				"X-RateLimit-Route-Policy": "default",
+				"X-RateLimit-Failure-Mode": "open",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, err := NewRateLimitHeaders(tt.styles)
+			if err != nil {
+				t.Fatalf("rate limit headers: %v", err)
+			}
+			header := make(http.Header)
+			headers.Write(header, tt.info, now)
+
+			for name, want := range tt.want {
+				if got := header.Get(name); got != want {
+					t.Errorf("%s %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewRateLimitHeadersRejectsUnknownStyle(t *testing.T) {
+	if _, err := NewRateLimitHeaders([]string{HeaderStyleLegacy, "draft-7"}); err == nil {
+		t.Error("unknown header style accepted")
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	for s, want := range map[string]string{
+		"api":       `"api"`,
+		`a"b\c`:     `"a\"b\\c"`,
+		"tab\there": `"tab_here"`,
+		"café":      `"caf_"`,
+	} {
+		if got := quoteString(s); got != want {
+			t.Errorf("quoteString(%q) = %s, want %s", s, got, want)
+		}
+	}
+}
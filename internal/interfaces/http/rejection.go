@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"html"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	"github.com/google/uuid"
+)
+
+// Reasons of rejections, for the {{reason}} placeholder
+const (
+	reasonRateLimited = "rate_limited"
+	reasonBanned      = "banned"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// reject answers a request rejected by the limits or the penalty box of route
+// with the rejection response of the route, or with message as plain text when
+// the route has none.
+func (h *HTTPHandler) reject(w http.ResponseWriter, r *http.Request, rejection config.RejectionConfig, info ports.RateLimitInfo, route, reason, message string) {
+	if !rejection.Enabled() {
+		http.Error(w, message, http.StatusTooManyRequests)
+		return
+	}
+
+	requestID := r.Header.Get(requestIDHeader)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	values := rejectionValues(info, route, reason, requestID, time.Now())
+
+	for name, value := range rejection.Headers {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(requestIDHeader, requestID)
+	if rejection.Redirect != "" {
+		w.Header().Set("Location", config.RenderTemplate(rejection.Redirect, values, url.QueryEscape))
+	}
+
+	status := rejection.StatusCode()
+	body, ok := negotiateBody(rejection.Bodies, r.Header.Values("Accept"))
+	switch {
+	case ok:
+		w.Header().Set("Content-Type", body.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(config.RenderTemplate(body.Template, values, escaperFor(body.ContentType))))
+	case rejection.Redirect != "":
+		w.WriteHeader(status)
+	default:
+		http.Error(w, message, status)
+	}
+}
+
+func rejectionValues(info ports.RateLimitInfo, route, reason, requestID string, now time.Time) map[string]string {
+	values := map[string]string{
+		config.PlaceholderLimit:      "0",
+		config.PlaceholderRemaining:  "0",
+		config.PlaceholderReset:      "0",
+		config.PlaceholderRetryAfter: strconv.FormatInt(retryAfterSeconds(info, now), 10),
+		config.PlaceholderRoute:      route,
+		config.PlaceholderRequestID:  requestID,
+		config.PlaceholderReason:     reason,
+	}
+	if info.Limit > 0 {
+		values[config.PlaceholderLimit] = strconv.Itoa(info.Limit)
+		values[config.PlaceholderRemaining] = strconv.Itoa(max(info.Remaining, 0))
+	}
+	if info.ResetTime > 0 {
+		values[config.PlaceholderReset] = strconv.FormatInt(max(info.ResetTime-now.Unix(), 0), 10)
+	}
+	return values
+}
+
+// negotiateBody returns the body whose content type the Accept header prefers,
+// the first body when none is acceptable, and false when there are no bodies.
+func negotiateBody(bodies []config.RejectionBody, accept []string) (config.RejectionBody, bool) {
+	if len(bodies) == 0 {
+		return config.RejectionBody{}, false
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := 0, 0.0
+	for i, body := range bodies {
+		mediaType, _, err := mime.ParseMediaType(body.ContentType)
+		if err != nil {
+			continue
+		}
+		if q := acceptQuality(ranges, mediaType); q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return bodies[best], true
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+func parseAccept(accept []string) []mediaRange {
+	var ranges []mediaRange
+	for _, element := range splitList(accept) {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(element))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality of the most specific range matching
+// mediaType, 0 when none does.
+func acceptQuality(ranges []mediaRange, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.mediaType == mediaType:
+			s = 2
+		case r.mediaType == mainType+"/*":
+			s = 1
+		case r.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// escaperFor escapes placeholder values for bodies of contentType: as JSON
+// string content or HTML text, and unchanged otherwise.
+func escaperFor(contentType string) func(string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return func(s string) string {
+			quoted, _ := json.Marshal(s)
+			return string(quoted[1 : len(quoted)-1])
+		}
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return html.EscapeString
+	default:
+		return func(s string) string { return s }
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+)
+
+// rejectRequest answers a request to route with the Accept header accept by
+// rejection.
+func rejectRequest(rejection config.RejectionConfig, route, accept string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	info := ports.RateLimitInfo{Limit: 10, Remaining: 0, ResetTime: time.Now().Unix() + 30, RetryAfter: 30 * time.Second}
+
+	w := httptest.NewRecorder()
+	(&HTTPHandler{}).reject(w, r, rejection, info, route, reasonRateLimited, "rate limit exceeded")
+	return w
+}
+
+func TestRejectPlainText(t *testing.T) {
+	w := rejectRequest(config.RejectionConfig{}, "api", "", nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status %d, want 429", w.Code)
+	}
+	if got := w.Body.String(); got != "rate limit exceeded\n" {
+		t.Errorf("body %q, want the plain text message", got)
+	}
+}
+
+func TestRejectNegotiatesBody(t *testing.T) {
+	rejection := config.RejectionConfig{
+		Status:  http.StatusServiceUnavailable,
+		Headers: map[string]string{"Cache-Control": "no-store"},
+		Bodies: []config.RejectionBody{
+			{ContentType: "application/json", Template: `{"route":"{{route}}","limit":{{limit}},"reason":"{{reason}}"}`},
+			{ContentType: "text/html; charset=utf-8", Template: "<p>{{route}} retry in {{retry_after}}s</p>"},
+		},
+	}
+	route := `<b>"api"</b>`
+
+	tests := []struct {
+		name, accept string
+		wantType     string
+		wantBody     string
+	}{
+		{name: "json", accept: "application/json", wantType: "application/json",
+			wantBody: `{"route":"\u003cb\u003e\"api\"\u003c/b\u003e","limit":10,"reason":"rate_limited"}`},
+		{name: "html by quality", accept: "application/json;q=0.5, text/*;q=0.9", wantType: "text/html; charset=utf-8",
+			wantBody: "<p>&lt;b&gt;&#34;api&#34;&lt;/b&gt; retry in 30s</p>"},
+		{name: "specific range over wildcard", accept: "*/*;q=0.1, text/html;q=0.2", wantType: "text/html; charset=utf-8"},
+		{name: "none acceptable", accept: "image/png", wantType: "application/json"},
+		{name: "no Accept header", wantType: "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := rejectRequest(rejection, route, tt.accept, nil)
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("status %d, want 503", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type %q, want %q", got, tt.wantType)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("Cache-Control %q, want no-store", got)
+			}
+			if w.Header().Get(requestIDHeader) == "" {
+				t.Errorf("no %s header", requestIDHeader)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body %s, want %s", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRejectRedirects(t *testing.T) {
+	rejection := config.RejectionConfig{Redirect: "https://example.com/slow-down?route={{route}}&id={{request_id}}"}
+
+	w := rejectRequest(rejection, "a&b", "", http.Header{"X-Request-Id": {"req 1"}})
+	if w.Code != http.StatusSeeOther {
+		t.Errorf("status %d, want 303", w.Code)
+	}
+	if got, want := w.Header().Get("Location"), "https://example.com/slow-down?route=a%26b&id=req+1"; got != want {
+		t.Errorf("Location %q, want %q", got, want)
+	}
+	if got := w.Header().Get(requestIDHeader); got != "req 1" {
+		t.Errorf("%s %q, want the request ID of the request", requestIDHeader, got)
+	}
+	if strings.TrimSpace(w.Body.String()) != "" {
+		t.Errorf("body %q, want none", w.Body)
+	}
+}
@@ -0,0 +1,156 @@
+// Package testutil holds the fixtures shared by the tests of the rate limiter:
+// a discarding logger, a static config and limiter services keeping their state
+// in memory. It is only imported by tests.
+package testutil
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	appConfig "github.com/SilentPlaces/rate_limiter/config"
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	domainLimiter "github.com/SilentPlaces/rate_limiter/internal/domain/limiter"
+	infraConfig "github.com/SilentPlaces/rate_limiter/internal/infrastructure/config"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/memory"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/metrics"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/tracing"
+	"github.com/SilentPlaces/rate_limiter/scripts/lua"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Algorithms are the limiter algorithms of services created by NewLimiterService.
+var Algorithms = map[string]domainLimiter.AlgorithmFactory{
+	config.AlgorithmFixedWindow:          limiter.FixedWindowLimiterFactory,
+	config.AlgorithmTokenBucket:          limiter.TokenBucketLimiterFactory,
+	config.AlgorithmSlidingWindow:        limiter.SlidingWindowLimiterFactory,
+	config.AlgorithmLeakyBucket:          limiter.LeakyBucketLimiterFactory,
+	config.AlgorithmGCRA:                 limiter.GCRALimiterFactory,
+	config.AlgorithmSlidingWindowCounter: limiter.SlidingWindowCounterLimiterFactory,
+	config.AlgorithmConcurrency:          limiter.ConcurrencyLimiterFactory,
+}
+
+// NopLogger discards every entry.
+type NopLogger struct{}
+
+func (NopLogger) Info(string, ...ports.Field)  {}
+func (NopLogger) Error(string, ...ports.Field) {}
+func (NopLogger) Debug(string, ...ports.Field) {}
+
+// StaticConfig serves the same config on every call.
+type StaticConfig struct {
+	Config config.Config
+}
+
+func (s StaticConfig) GetConfig() config.Config {
+	return s.Config
+}
+
+// ParseRoutes parses routesJSON as the routes of Consul, failing t when invalid.
+func ParseRoutes(t testing.TB, routesJSON string) config.Config {
+	t.Helper()
+	cfg, err := infraConfig.NewParser().Parse([]byte(routesJSON))
+	if err != nil {
+		t.Fatalf("parse routes: %v", err)
+	}
+	return cfg
+}
+
+// Scripts returns the Lua scripts of the limiter by name, the multi limit
+// script being built for algorithms.
+func Scripts(t testing.TB, algorithms ...string) map[string]string {
+	t.Helper()
+	scripts := make(map[string]string)
+	for _, name := range append(append([]string{}, algorithms...), memory.MultiLimitScript, memory.PenaltyScript) {
+		body, err := lua.Scripts.ReadFile(name + ".lua")
+		if err != nil {
+			t.Fatalf("read script %s: %v", name, err)
+		}
+		scripts[name] = string(body)
+	}
+	algorithmScripts := make(map[string]string, len(algorithms))
+	for _, algo := range algorithms {
+		algorithmScripts[algo] = scripts[algo]
+	}
+	scripts[memory.MultiLimitScript] = limiter.BuildMultiLimitScript(scripts[memory.MultiLimitScript], algorithmScripts)
+	return scripts
+}
+
+// LimiterService is a limiter service with the tracer and metrics it reports to.
+type LimiterService struct {
+	*service.LimiterService
+	Tracer  ports.Tracer
+	Metrics *metrics.PrometheusMetrics
+}
+
+// NewLimiterService creates a limiter service applying routesJSON with every
+// algorithm, keeping its state in memory. Spans are not recorded.
+func NewLimiterService(t testing.TB, routesJSON string) LimiterService {
+	t.Helper()
+	ctx := context.Background()
+
+	algorithms := make([]string, 0, len(Algorithms))
+	for algo := range Algorithms {
+		algorithms = append(algorithms, algo)
+	}
+	scripts := Scripts(t, algorithms...)
+	store, err := memory.NewMemoryStore(NopLogger{}, scripts)
+	if err != nil {
+		t.Fatalf("memory store: %v", err)
+	}
+	sha1s := make(map[string]string, len(scripts))
+	for name, script := range scripts {
+		if sha1s[name], err = store.ScriptLoad(ctx, script); err != nil {
+			t.Fatalf("load script %s: %v", name, err)
+		}
+	}
+	limiters := make(map[string]ports.RateLimiter, len(Algorithms))
+	for algo, factory := range Algorithms {
+		limiters[algo] = factory(store, sha1s[algo])
+	}
+
+	policy, err := domainLimiter.NewPolicy(nil)
+	if err != nil {
+		t.Fatalf("policy: %v", err)
+	}
+	tracer, err := tracing.NewOtelTracer(ctx, appConfig.TracingConfig{})
+	if err != nil {
+		t.Fatalf("tracer: %v", err)
+	}
+	promMetrics := metrics.NewPrometheusMetrics()
+
+	limiterSvc := service.NewLimiterService(NopLogger{}, StaticConfig{Config: ParseRoutes(t, routesJSON)}, limiters,
+		limiter.NewMultiLimiter(store, sha1s[memory.MultiLimitScript], limiters),
+		limiter.NewLocalLimiter(),
+		limiter.NewPenaltyBox(store, sha1s[memory.PenaltyScript]),
+		policy, promMetrics, tracer)
+	return LimiterService{LimiterService: limiterSvc, Tracer: tracer, Metrics: promMetrics}
+}
+
+// Dial serves the services registered by register on an in-process listener,
+// returning a connection to it. Both are closed when t ends.
+func Dial(t testing.TB, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	register(server)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
@@ -0,0 +1,42 @@
+package ratelimiter
+
+import (
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	infraConfig "github.com/SilentPlaces/rate_limiter/internal/infrastructure/config"
+)
+
+// ParseConfig parses routes in the JSON format the rate limiter server reads
+// from Consul.
+func ParseConfig(data []byte) (Config, error) {
+	return infraConfig.NewParser().Parse(data)
+}
+
+// StaticConfig returns a ConfigService always returning cfg. Services reloading
+// their routes implement ConfigService instead, GetConfig being called on
+// every request.
+func StaticConfig(cfg Config) ConfigService {
+	return staticConfig{config: cfg}
+}
+
+type staticConfig struct {
+	config Config
+}
+
+func (s staticConfig) GetConfig() Config {
+	return s.config
+}
+
+// failureConfig applies the failure mode given to New to configs without a
+// global one. Routes with a failure mode of their own keep it.
+type failureConfig struct {
+	ConfigService
+	mode string
+}
+
+func (f failureConfig) GetConfig() Config {
+	cfg := f.ConfigService.GetConfig()
+	if cfg.Failure.Mode == "" {
+		cfg.Failure = config.FailureConfig{Mode: f.mode}
+	}
+	return cfg
+}
@@ -0,0 +1,43 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/SilentPlaces/rate_limiter/pkg/ratelimiter"
+)
+
+func Example() {
+	ctx := context.Background()
+
+	store, err := ratelimiter.NewMemoryStore(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg, err := ratelimiter.ParseConfig([]byte(`{
+	  "routes": {
+	    "jobs": { "algorithm": "fixed_window", "limit": 10, "window": 60 }
+	  }
+	}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	limiter, err := ratelimiter.New(ctx, store, ratelimiter.StaticConfig(cfg),
+		ratelimiter.WithFailureMode(ratelimiter.FailureModeLocal))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, cost := range []int{4, 4, 4} {
+		result, err := limiter.Allow(ctx, ratelimiter.Request{Route: "jobs", Key: "tenant-42", Cost: cost})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("allowed=%t remaining=%d\n", result.Allowed, result.Remaining)
+	}
+	// Output:
+	// allowed=true remaining=6
+	// allowed=true remaining=2
+	// allowed=false remaining=2
+}
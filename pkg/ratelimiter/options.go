@@ -0,0 +1,104 @@
+package ratelimiter
+
+import (
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+)
+
+// Option configures a Limiter.
+type Option func(*options)
+
+type options struct {
+	logger          Logger
+	failureMode     string
+	route           RouteFunc
+	key             KeyFunc
+	reject          RejectFunc
+	trustedProxies  []string
+	clientIPHeaders []string
+	headerStyles    []string
+	whitelistedIPs  []string
+}
+
+// WithLogger logs the decisions of the limiter to log. Nothing is logged by default.
+func WithLogger(log Logger) Option {
+	return func(o *options) {
+		o.logger = log
+	}
+}
+
+// WithFailureMode decides requests with mode while the store is unavailable,
+// its errors wrapping ErrStoreUnavailable:
+// FailureModeOpen (default) allows them, FailureModeClosed rejects them and
+// FailureModeLocal enforces the limits in process. It applies when the config
+// has no global failure mode; routes with their own keep it.
+func WithFailureMode(mode string) Option {
+	return func(o *options) {
+		o.failureMode = mode
+	}
+}
+
+// WithRouteFunc selects the route of requests with route. By default requests
+// are routed by the route matchers of the config, falling back to the
+// X-Rate-Limit-Rule header of the WithTrustedProxies peers.
+func WithRouteFunc(route RouteFunc) Option {
+	return func(o *options) {
+		o.route = route
+	}
+}
+
+// WithKeyFunc keys the clients of the middleware with key. By default clients
+// are keyed by the key definition of their route, or by IP.
+func WithKeyFunc(key KeyFunc) Option {
+	return func(o *options) {
+		o.key = key
+	}
+}
+
+// WithRejectFunc answers requests denied by the middleware with reject. By
+// default they get the rejection response of their route, or a plain text
+// 429, 403 or 503 error.
+func WithRejectFunc(reject RejectFunc) Option {
+	return func(o *options) {
+		o.reject = reject
+	}
+}
+
+// WithTrustedProxies believes the client IP headers of requests from the given
+// addresses and CIDR ranges, X-Forwarded-For unless headers are given. The
+// middleware otherwise keys clients by the address of the peer.
+func WithTrustedProxies(proxies []string, headers ...string) Option {
+	return func(o *options) {
+		o.trustedProxies = proxies
+		o.clientIPHeaders = headers
+	}
+}
+
+// WithHeaderStyles selects the rate limit headers of responses, "legacy"
+// X-RateLimit-* headers by default, or "ietf" RateLimit and RateLimit-Policy
+// headers.
+func WithHeaderStyles(styles ...string) Option {
+	return func(o *options) {
+		o.headerStyles = styles
+	}
+}
+
+// WithWhitelistedIPs lets the given addresses and CIDR ranges bypass rate limiting.
+func WithWhitelistedIPs(ips ...string) Option {
+	return func(o *options) {
+		o.whitelistedIPs = ips
+	}
+}
+
+// discardLogger is the Logger of limiters and stores created without one.
+type discardLogger struct{}
+
+func (discardLogger) Info(string, ...ports.Field)  {}
+func (discardLogger) Error(string, ...ports.Field) {}
+func (discardLogger) Debug(string, ...ports.Field) {}
+
+func loggerOrDiscard(log Logger) Logger {
+	if log == nil {
+		return discardLogger{}
+	}
+	return log
+}
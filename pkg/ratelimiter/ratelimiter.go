@@ -0,0 +1,214 @@
+// Package ratelimiter embeds the rate limiter in Go services instead of running
+// it as a sidecar. It applies the algorithms, stacked limits, penalty box,
+// access lists and failure modes of the rate limiter server, configured with
+// the same JSON routes, through a Limiter and its net/http middleware:
+//
+//	store, err := ratelimiter.NewMemoryStore(nil)
+//	cfg, err := ratelimiter.ParseConfig(routesJSON)
+//	limiter, err := ratelimiter.New(ctx, store, ratelimiter.StaticConfig(cfg),
+//		ratelimiter.WithFailureMode(ratelimiter.FailureModeLocal))
+//	http.ListenAndServe(":8080", limiter.Middleware(mux))
+package ratelimiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	appConfig "github.com/SilentPlaces/rate_limiter/config"
+	"github.com/SilentPlaces/rate_limiter/internal/application/ports"
+	"github.com/SilentPlaces/rate_limiter/internal/application/service"
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	domainErrors "github.com/SilentPlaces/rate_limiter/internal/domain/errors"
+	domainLimiter "github.com/SilentPlaces/rate_limiter/internal/domain/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/memory"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/metrics"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/tracing"
+	handler "github.com/SilentPlaces/rate_limiter/internal/interfaces/http"
+)
+
+type (
+	// Store keeps the state of the limits: NewMemoryStore, NewRedisStore or any
+	// store able to run the Lua scripts of the limiter. Stores report failures
+	// to reach their backend with ErrStoreUnavailable.
+	Store = ports.LimiterScore
+	// KeyState is the raw state kept under a key, as returned by Store.Inspect.
+	KeyState     = ports.KeyState
+	ScoredMember = ports.ScoredMember
+	// ConfigService returns the routes to apply, on every request.
+	ConfigService = ports.ConfigService
+	// Config holds the routes, as returned by ParseConfig.
+	Config = config.Config
+	// RouteConfig is the config of a single route.
+	RouteConfig = config.RouteConfig
+	// Result is the decision on a request, with the state of its limits. When
+	// Lease is set, it must be released once the request completes.
+	Result = ports.RateLimitInfo
+	Lease  = ports.Lease
+	Logger = ports.Logger
+	Field  = ports.Field
+
+	// RouteFunc returns the route of a request.
+	RouteFunc = handler.RouteFunc
+	// KeyFunc returns the client key of a request to a route, or an empty key
+	// to key the client by IP. An error rejects the request with 401. Keys
+	// never share the limits of an IP, as the keys of Request.
+	KeyFunc = handler.KeyFunc
+	// RejectFunc answers a request denied by the middleware.
+	RejectFunc = handler.RejectFunc
+)
+
+// Key types of KeyState
+const (
+	KeyTypeNone      = ports.KeyTypeNone
+	KeyTypeString    = ports.KeyTypeString
+	KeyTypeHash      = ports.KeyTypeHash
+	KeyTypeSortedSet = ports.KeyTypeSortedSet
+)
+
+// Failure modes, see WithFailureMode
+const (
+	FailureModeOpen   = config.FailureModeOpen
+	FailureModeClosed = config.FailureModeClosed
+	FailureModeLocal  = config.FailureModeLocal
+)
+
+var (
+	// ErrNoClient is returned by Allow for requests with neither a key nor an IP.
+	ErrNoClient = errors.New("ratelimiter: request has neither a key nor an IP")
+	// ErrStoreUnavailable is wrapped by the errors of stores failing to reach
+	// their backend, the requests then being decided by the failure mode. Other
+	// store errors are returned by Allow, or answered 500 by the middleware.
+	ErrStoreUnavailable error = domainErrors.ErrStoreUnavailable
+)
+
+// Request is a request to decide with Allow.
+type Request struct {
+	// Route selects the limits, the unknown route policy applying to routes
+	// missing from the config.
+	Route string
+	// Key identifies the client, falling back to IP when empty. Keys never
+	// share the limits of an IP.
+	Key string
+	// IP is the client IP, matched against the access lists and whitelist.
+	IP string
+	// Cost is the number of units consumed, 1 when zero, capped at the max cost
+	// of the route.
+	Cost int
+}
+
+// Limiter decides requests against the routes of its ConfigService.
+type Limiter struct {
+	service *service.LimiterService
+	handler *handler.HTTPHandler
+	metrics *metrics.PrometheusMetrics
+}
+
+// New creates a limiter keeping state in store and applying the routes of
+// configs, loading the Lua scripts of the algorithms into store.
+func New(ctx context.Context, store Store, configs ConfigService, opts ...Option) (*Limiter, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	log := loggerOrDiscard(o.logger)
+
+	if o.failureMode != "" {
+		if err := (config.FailureConfig{Mode: o.failureMode}).Validate(); err != nil {
+			return nil, err
+		}
+		configs = failureConfig{ConfigService: configs, mode: o.failureMode}
+	}
+
+	promMetrics := metrics.NewPrometheusMetrics()
+	store = metrics.NewInstrumentedStore(store, promMetrics)
+
+	scripts, err := luaScripts()
+	if err != nil {
+		return nil, err
+	}
+	scriptSHA1s := make(map[string]string, len(scripts))
+	for name, script := range scripts {
+		sha1, err := store.ScriptLoad(ctx, script)
+		if err != nil {
+			return nil, fmt.Errorf("load script %s: %w", name, err)
+		}
+		scriptSHA1s[name] = sha1
+	}
+
+	limiters := make(map[string]ports.RateLimiter, len(algorithms))
+	for algo, factory := range algorithms {
+		limiters[algo] = factory(store, scriptSHA1s[algo])
+	}
+
+	policy, err := domainLimiter.NewPolicy(o.whitelistedIPs)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := config.ParseIPRanges(o.trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	clientIP, err := handler.NewClientIPResolver(trustedProxies, o.clientIPHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("client IP resolver: %w", err)
+	}
+	headers, err := handler.NewRateLimitHeaders(o.headerStyles)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit headers: %w", err)
+	}
+	// Spans are not recorded, tracing being left to the service
+	tracer, err := tracing.NewOtelTracer(ctx, appConfig.TracingConfig{})
+	if err != nil {
+		return nil, err
+	}
+
+	limiterSvc := service.NewLimiterService(log, configs, limiters,
+		limiter.NewMultiLimiter(store, scriptSHA1s[memory.MultiLimitScript], limiters),
+		limiter.NewLocalLimiter(),
+		limiter.NewPenaltyBox(store, scriptSHA1s[memory.PenaltyScript]),
+		policy, promMetrics, tracer)
+
+	return &Limiter{
+		service: limiterSvc,
+		handler: &handler.HTTPHandler{
+			LimiterService: limiterSvc,
+			Logger:         log,
+			Metrics:        promMetrics,
+			Tracer:         tracer,
+			ClientIP:       clientIP,
+			Headers:        headers,
+			Route:          o.route,
+			ClientKey:      o.key,
+			Reject:         o.reject,
+		},
+		metrics: promMetrics,
+	}, nil
+}
+
+// Allow decides req, consuming its cost when allowed. Requests are rejected
+// by their result rather than an error; errors are left to the caller to
+// allow or reject.
+func (l *Limiter) Allow(ctx context.Context, req Request) (Result, error) {
+	if req.Key == "" && req.IP == "" {
+		return Result{}, ErrNoClient
+	}
+	var clientKey string
+	if req.Key != "" {
+		clientKey = config.CallerKeyPrefix + req.Key
+	}
+	return l.service.AllowWithInfo(ctx, req.IP, req.Route, clientKey, req.Cost)
+}
+
+// Middleware decides requests before passing allowed ones on to next, with
+// their rate limit headers. Denied requests are answered by the RejectFunc.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return handler.NewMiddleware(l.handler, next)
+}
+
+// MetricsHandler serves the metrics of the limiter in the Prometheus text format.
+func (l *Limiter) MetricsHandler() http.Handler {
+	return l.metrics.Handler()
+}
@@ -0,0 +1,272 @@
+package ratelimiter_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/SilentPlaces/rate_limiter/pkg/ratelimiter"
+)
+
+// routesJSON limits /api/ to 2 units a minute, costing at most 2, and /jobs/ to
+// a single job in flight, denying 192.0.2.0/24.
+const routesJSON = `{
+  "access": { "deny": ["192.0.2.0/24"] },
+  "routes": {
+    "api": {
+      "algorithm": "fixed_window",
+      "limit": 2,
+      "window": 60,
+      "match": { "prefix": "/api/" },
+      "cost": { "max": 2 }
+    },
+    "jobs": {
+      "algorithm": "concurrency",
+      "limit": 1,
+      "lease_ttl": 60,
+      "match": { "prefix": "/jobs/" }
+    }
+  }
+}`
+
+func newLimiter(t *testing.T, store ratelimiter.Store, opts ...ratelimiter.Option) *ratelimiter.Limiter {
+	t.Helper()
+	if store == nil {
+		var err error
+		if store, err = ratelimiter.NewMemoryStore(nil); err != nil {
+			t.Fatalf("memory store: %v", err)
+		}
+	}
+	cfg, err := ratelimiter.ParseConfig([]byte(routesJSON))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	limiter, err := ratelimiter.New(context.Background(), store, ratelimiter.StaticConfig(cfg), opts...)
+	if err != nil {
+		t.Fatalf("new limiter: %v", err)
+	}
+	return limiter
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+})
+
+// serve sends a GET of path from the client at ip through handler.
+func serve(handler http.Handler, ip, path string, header ...string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.RemoteAddr = ip + ":50000"
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := newLimiter(t, nil).Middleware(okHandler)
+
+	for i, want := range []string{"1", "0"} {
+		w := serve(handler, "203.0.113.1", "/api/users")
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Fatalf("request %d: %d %q, want 200 ok", i, w.Code, w.Body)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("request %d: X-RateLimit-Remaining %q, want %s", i, got, want)
+		}
+	}
+
+	// The default rejection answers 429 with Retry-After, other clients keeping
+	// limits of their own
+	w := serve(handler, "203.0.113.1", "/api/users")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("over limit: %d with Retry-After %q, want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := serve(handler, "203.0.113.2", "/api/users"); w.Code != http.StatusOK {
+		t.Errorf("other client status %d, want 200", w.Code)
+	}
+}
+
+func TestWithKeyFunc(t *testing.T) {
+	handler := newLimiter(t, nil, ratelimiter.WithKeyFunc(
+		func(r *http.Request, _ ratelimiter.RouteConfig, _ string) (string, error) {
+			tenant := r.Header.Get("X-Tenant")
+			if tenant == "invalid" {
+				return "", errors.New("invalid tenant")
+			}
+			return tenant, nil
+		},
+	)).Middleware(okHandler)
+
+	for i := 0; i < 2; i++ {
+		serve(handler, "203.0.113.1", "/api/users", "X-Tenant", "a")
+	}
+	if w := serve(handler, "203.0.113.1", "/api/users", "X-Tenant", "a"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("tenant a status %d, want 429", w.Code)
+	}
+	if w := serve(handler, "203.0.113.1", "/api/users", "X-Tenant", "b"); w.Code != http.StatusOK {
+		t.Errorf("tenant b status %d, want 200", w.Code)
+	}
+
+	// A key equal to an IP doesn't take the limits of the IP
+	for i := 0; i < 2; i++ {
+		serve(handler, "203.0.113.9", "/api/users", "X-Tenant", "203.0.113.9")
+	}
+	if w := serve(handler, "203.0.113.9", "/api/users"); w.Code != http.StatusOK {
+		t.Errorf("client keyed by IP status %d, want 200", w.Code)
+	}
+
+	if w := serve(handler, "203.0.113.1", "/api/users", "X-Tenant", "invalid"); w.Code != http.StatusUnauthorized {
+		t.Errorf("key error status %d, want 401", w.Code)
+	}
+}
+
+func TestWithRejectFunc(t *testing.T) {
+	handler := newLimiter(t, nil, ratelimiter.WithRejectFunc(
+		func(w http.ResponseWriter, _ *http.Request, info ratelimiter.Result) {
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = fmt.Fprintf(w, "blocked=%t remaining=%d", info.Blocked, info.Remaining)
+		},
+	)).Middleware(okHandler)
+
+	for i := 0; i < 2; i++ {
+		serve(handler, "203.0.113.1", "/api/users")
+	}
+	w := serve(handler, "203.0.113.1", "/api/users")
+	if w.Code != http.StatusTeapot || w.Body.String() != "blocked=false remaining=0" {
+		t.Errorf("over limit: %d %q, want 418 blocked=false remaining=0", w.Code, w.Body)
+	}
+	w = serve(handler, "192.0.2.7", "/api/users")
+	if w.Code != http.StatusTeapot || w.Body.String() != "blocked=true remaining=-1" {
+		t.Errorf("denied client: %d %q, want 418 blocked=true remaining=-1", w.Code, w.Body)
+	}
+}
+
+// failingStore loads the scripts into a memory store but fails to run them,
+// with err.
+type failingStore struct {
+	ratelimiter.Store
+	err error
+}
+
+func (s failingStore) EvalSha(context.Context, string, []string, ...[]interface{}) (interface{}, error) {
+	return nil, s.err
+}
+
+func newFailingStore(t *testing.T, err error) ratelimiter.Store {
+	t.Helper()
+	store, memErr := ratelimiter.NewMemoryStore(nil)
+	if memErr != nil {
+		t.Fatalf("memory store: %v", memErr)
+	}
+	return failingStore{Store: store, err: err}
+}
+
+func TestFailureModes(t *testing.T) {
+	unavailable := fmt.Errorf("dial tcp: connection refused: %w", ratelimiter.ErrStoreUnavailable)
+
+	tests := []struct {
+		mode string
+		// want are the statuses of 3 requests
+		want []int
+	}{
+		{mode: ratelimiter.FailureModeOpen, want: []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		{mode: ratelimiter.FailureModeClosed, want: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}},
+		{mode: ratelimiter.FailureModeLocal, want: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			handler := newLimiter(t, newFailingStore(t, unavailable), ratelimiter.WithFailureMode(tt.mode)).Middleware(okHandler)
+			for i, want := range tt.want {
+				w := serve(handler, "203.0.113.1", "/api/users")
+				if w.Code != want {
+					t.Fatalf("request %d: status %d, want %d", i, w.Code, want)
+				}
+				if got := w.Header().Get("X-RateLimit-Failure-Mode"); got != tt.mode {
+					t.Errorf("request %d: X-RateLimit-Failure-Mode %q, want %s", i, got, tt.mode)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	// Errors other than ErrStoreUnavailable fail the request
+	limiter := newLimiter(t, newFailingStore(t, errors.New("NOSCRIPT")), ratelimiter.WithFailureMode(ratelimiter.FailureModeOpen))
+
+	if w := serve(limiter.Middleware(okHandler), "203.0.113.1", "/api/users"); w.Code != http.StatusInternalServerError {
+		t.Errorf("status %d, want 500", w.Code)
+	}
+	if _, err := limiter.Allow(context.Background(), ratelimiter.Request{Route: "api", IP: "203.0.113.1"}); err == nil {
+		t.Error("Allow returned no error")
+	}
+}
+
+func TestAllow(t *testing.T) {
+	limiter := newLimiter(t, nil)
+	ctx := context.Background()
+
+	if _, err := limiter.Allow(ctx, ratelimiter.Request{Route: "api"}); !errors.Is(err, ratelimiter.ErrNoClient) {
+		t.Errorf("request without client: %v, want ErrNoClient", err)
+	}
+
+	// The cost is capped at the max cost of the route
+	result, err := limiter.Allow(ctx, ratelimiter.Request{Route: "api", Key: "203.0.113.5", Cost: 100})
+	if err != nil || !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("first request: %+v %v, want allowed with 0 remaining", result, err)
+	}
+	if result, _ = limiter.Allow(ctx, ratelimiter.Request{Route: "api", Key: "203.0.113.5"}); result.Allowed {
+		t.Errorf("request over limit allowed: %+v", result)
+	}
+
+	// Keys don't share the limits of IPs
+	if result, _ = limiter.Allow(ctx, ratelimiter.Request{Route: "api", IP: "203.0.113.5"}); !result.Allowed {
+		t.Errorf("client keyed by IP denied: %+v", result)
+	}
+}
+
+func TestAllowLeaseRelease(t *testing.T) {
+	limiter := newLimiter(t, nil)
+	ctx := context.Background()
+	req := ratelimiter.Request{Route: "jobs", Key: "tenant-42"}
+
+	result, err := limiter.Allow(ctx, req)
+	if err != nil || !result.Allowed || result.Lease == nil {
+		t.Fatalf("first job: %+v %v, want allowed with a lease", result, err)
+	}
+	if second, _ := limiter.Allow(ctx, req); second.Allowed {
+		t.Fatal("second job allowed while the first is in flight")
+	}
+
+	if err := result.Lease.Release(ctx); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if third, _ := limiter.Allow(ctx, req); !third.Allowed {
+		t.Error("job denied once the first was released")
+	}
+}
+
+func TestMiddlewareHoldsLeaseUntilServed(t *testing.T) {
+	limiter := newLimiter(t, nil)
+	var nested int
+	var handler http.Handler
+	handler = limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nested") == "" {
+			nested = serve(handler, "203.0.113.1", "/jobs/run", "X-Nested", "1").Code
+		}
+	}))
+
+	if w := serve(handler, "203.0.113.1", "/jobs/run"); w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+	if nested != http.StatusTooManyRequests {
+		t.Errorf("request while another is served: status %d, want 429", nested)
+	}
+	if w := serve(handler, "203.0.113.1", "/jobs/run"); w.Code != http.StatusOK {
+		t.Errorf("request once the other was served: status %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,83 @@
+package ratelimiter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SilentPlaces/rate_limiter/internal/domain/config"
+	domainLimiter "github.com/SilentPlaces/rate_limiter/internal/domain/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/limiter"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/memory"
+	redis2 "github.com/SilentPlaces/rate_limiter/internal/infrastructure/redis"
+	"github.com/SilentPlaces/rate_limiter/internal/infrastructure/resilience"
+	"github.com/SilentPlaces/rate_limiter/scripts/lua"
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis store defaults, those of the rate limiter server
+const (
+	redisOperationTimeoutSeconds = 10
+	circuitBreakerMaxFailures    = 5
+	circuitBreakerTimeout        = 30 * time.Second
+)
+
+// algorithms maps every limiter algorithm, implemented by the embedded Lua
+// script of its name, to its factory.
+var algorithms = map[string]domainLimiter.AlgorithmFactory{
+	config.AlgorithmFixedWindow:          limiter.FixedWindowLimiterFactory,
+	config.AlgorithmTokenBucket:          limiter.TokenBucketLimiterFactory,
+	config.AlgorithmSlidingWindow:        limiter.SlidingWindowLimiterFactory,
+	config.AlgorithmLeakyBucket:          limiter.LeakyBucketLimiterFactory,
+	config.AlgorithmGCRA:                 limiter.GCRALimiterFactory,
+	config.AlgorithmSlidingWindowCounter: limiter.SlidingWindowCounterLimiterFactory,
+	config.AlgorithmConcurrency:          limiter.ConcurrencyLimiterFactory,
+}
+
+// NewMemoryStore creates a store keeping limiter state in process memory, for
+// services running a single instance. log may be nil.
+func NewMemoryStore(log Logger) (Store, error) {
+	scripts, err := luaScripts()
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemoryStore(loggerOrDiscard(log), scripts)
+}
+
+// NewRedisStore creates a store keeping limiter state in Redis, shared by every
+// instance of the service. Operations go through a circuit breaker, opening
+// after 5 consecutive failures for 30 seconds, during which requests are
+// decided by the failure mode. log may be nil.
+func NewRedisStore(client *redis.Client, log Logger) Store {
+	log = loggerOrDiscard(log)
+	return redis2.NewResilientRedisAdapter(
+		redis2.NewRedisAdapter(client, log, redisOperationTimeoutSeconds),
+		log,
+		resilience.NewCircuitBreaker(circuitBreakerMaxFailures, circuitBreakerTimeout),
+	)
+}
+
+// luaScripts returns the embedded Lua scripts keyed by algorithm name,
+// memory.MultiLimitScript and memory.PenaltyScript, the multi limit script
+// embedding every algorithm script.
+func luaScripts() (map[string]string, error) {
+	names := []string{memory.MultiLimitScript, memory.PenaltyScript}
+	for algo := range algorithms {
+		names = append(names, algo)
+	}
+
+	scripts := make(map[string]string, len(names))
+	for _, name := range names {
+		body, err := lua.Scripts.ReadFile(name + ".lua")
+		if err != nil {
+			return nil, fmt.Errorf("lua script %s: %w", name, err)
+		}
+		scripts[name] = string(body)
+	}
+
+	algorithmScripts := make(map[string]string, len(algorithms))
+	for algo := range algorithms {
+		algorithmScripts[algo] = scripts[algo]
+	}
+	scripts[memory.MultiLimitScript] = limiter.BuildMultiLimitScript(scripts[memory.MultiLimitScript], algorithmScripts)
+	return scripts, nil
+}
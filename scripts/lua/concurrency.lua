@@ -0,0 +1,50 @@
+-- Concurrency (in-flight request) Limiter
+-- Each in-flight request holds a lease stored as a ZSET member scored by its
+-- expiry, so leases of crashed instances are reclaimed once they expire.
+
+local key = KEYS[1]
+local action = ARGV[1] -- "acquire" or "release"
+
+if action == 'release' then
+    local lease_id = ARGV[2]
+    local cost = tonumber(ARGV[3])
+    local removed = 0
+    for i = 1, cost do
+        removed = removed + redis.call('ZREM', key, lease_id .. ':' .. i)
+    end
+    return {removed}
+end
+
+local limit = tonumber(ARGV[2])    -- Maximum concurrent requests
+local lease_ttl = tonumber(ARGV[3]) -- Lease TTL (in milliseconds)
+local now = tonumber(ARGV[4])       -- Current timestamp (in milliseconds)
+local lease_id = ARGV[5]
+local cost = tonumber(ARGV[6])      -- Slots held by this request
+local dry_run = ARGV[7] == '1'      -- Check only, don't acquire
+
+-- Drop leases that were never released
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now)
+
+local in_flight = redis.call('ZCARD', key)
+
+if in_flight + cost > limit then
+    -- The earliest a slot is guaranteed to free up is when the oldest lease expires
+    local reset_time = 0
+    local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+    if #oldest >= 2 then
+        reset_time = math.ceil(tonumber(oldest[2]) / 1000)
+    end
+    return {0, in_flight, math.max(limit - in_flight, 0), reset_time}
+end
+
+if dry_run then
+    return {1, in_flight + cost, limit - in_flight - cost, 0}
+end
+
+-- One member per held slot, all expiring together
+for i = 1, cost do
+    redis.call('ZADD', key, now + lease_ttl, lease_id .. ':' .. i)
+end
+redis.call('PEXPIRE', key, lease_ttl)
+
+return {1, in_flight + cost, limit - in_flight - cost, 0}
@@ -2,20 +2,30 @@
 local key = KEYS[1]
 local window = tonumber(ARGV[1]) -- window time
 local limit = tonumber(ARGV[2]) -- limit count
+local cost = tonumber(ARGV[3]) -- units consumed by this request
+local dry_run = ARGV[4] == "1" -- check only, don't consume
 
--- increment counter
-local current = redis.call("INCR", key)
-
--- get ttl of key
+-- get current counter and ttl of key
+local current = tonumber(redis.call("GET", key)) or 0
 local ttl = redis.call("TTL", key)
-if current == 1 then
+
+-- check for limit before consuming, so rejected requests don't count
+if current + cost > limit then
+    return {0, current, math.max(limit - current, 0), ttl}
+end
+
+if dry_run then
+    if ttl < 0 then
+        ttl = window
+    end
+    return {1, current + cost, limit - current - cost, ttl}
+end
+
+-- increment counter
+current = redis.call("INCRBY", key, cost)
+if ttl < 0 then
     redis.call("EXPIRE", key, window)
     ttl = window
 end
 
--- check for limit
-if current > limit then
-    return {0, current, 0, ttl} 
-else
-    return {1, current, limit - current, ttl} 
-end
+return {1, current, limit - current, ttl}
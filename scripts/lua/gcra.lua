@@ -0,0 +1,33 @@
+-- GCRA (Generic Cell Rate Algorithm) Rate Limiter
+-- Stores only the theoretical arrival time (TAT) of the next request per key.
+
+local key = KEYS[1]
+local emission_interval = tonumber(ARGV[1]) -- Milliseconds between requests at the steady rate
+local burst = tonumber(ARGV[2])             -- Maximum requests allowed at once
+local now = tonumber(ARGV[3])               -- Current timestamp (in milliseconds)
+local cost = tonumber(ARGV[4])              -- Units consumed by this request
+local dry_run = ARGV[5] == '1'              -- Check only, don't consume
+
+local delay_tolerance = emission_interval * burst
+
+-- A missing or past TAT means the client has its full burst available
+local tat = tonumber(redis.call('GET', key)) or now
+tat = math.max(tat, now)
+
+local new_tat = tat + emission_interval * cost
+local allow_at = new_tat - delay_tolerance
+
+-- Too early, reject and report when the request would conform
+if now < allow_at then
+    local retry_after = math.ceil(allow_at - now)
+    local remaining = math.max(math.floor((now - (tat - delay_tolerance)) / emission_interval), 0)
+    return {0, retry_after, remaining, math.ceil(allow_at / 1000)}
+end
+
+-- Save the new TAT until the client is fully replenished
+if not dry_run then
+    redis.call('SET', key, new_tat, 'PX', math.ceil(new_tat - now))
+end
+
+local remaining = math.floor((now - allow_at) / emission_interval)
+return {1, 0, remaining, math.ceil(new_tat / 1000)}
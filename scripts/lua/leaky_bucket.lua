@@ -0,0 +1,41 @@
+-- Leaky Bucket Rate Limiter (queue-and-drain)
+
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])  -- Maximum number of queued requests
+local leak_rate = tonumber(ARGV[2]) -- Requests drained per second
+local now = tonumber(ARGV[3])       -- Current timestamp (in milliseconds)
+local cost = tonumber(ARGV[4])      -- Queue slots taken by this request
+local dry_run = ARGV[5] == '1'      -- Check only, don't consume
+
+-- Get current bucket state
+local bucket = redis.call('HMGET', key, 'level', 'last_leak')
+local level = tonumber(bucket[1]) or 0
+local last_leak = tonumber(bucket[2]) or now
+
+-- Drain the bucket at a constant rate since the last request
+local elapsed = math.max(0, now - last_leak)
+level = math.max(0, level - (elapsed * leak_rate / 1000))
+
+-- Keep the key until a full bucket would have drained
+local bucket_ttl = math.ceil(capacity / leak_rate) + 1
+
+-- Bucket is full, reject and report when the next slot frees up
+if level + cost > capacity then
+    local wait_ms = math.ceil((level + cost - capacity) * 1000 / leak_rate)
+    if not dry_run then
+        redis.call('HSET', key, 'level', level, 'last_leak', now)
+        redis.call('EXPIRE', key, bucket_ttl)
+    end
+    return {0, math.ceil(level), math.max(math.floor(capacity - level), 0), math.ceil((now + wait_ms) / 1000), 0}
+end
+
+-- Requests already queued must drain before this one leaves the bucket
+local delay_ms = math.floor(level * 1000 / leak_rate)
+level = level + cost
+if not dry_run then
+    redis.call('HSET', key, 'level', level, 'last_leak', now)
+    redis.call('EXPIRE', key, bucket_ttl)
+end
+
+local reset_time = math.ceil((now + level * 1000 / leak_rate) / 1000)
+return {1, math.ceil(level), math.floor(capacity - level), reset_time, delay_ms}
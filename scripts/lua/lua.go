@@ -0,0 +1,10 @@
+// Package lua embeds the Lua scripts of the limiter algorithms, for binaries
+// that run without the scripts directory, such as services embedding the limiter.
+package lua
+
+import "embed"
+
+// Scripts holds every <name>.lua script of this directory.
+//
+//go:embed *.lua
+var Scripts embed.FS
@@ -0,0 +1,67 @@
+-- Stacked limits: evaluates several limits atomically
+-- This script is not loaded on its own. At startup every algorithm script is
+-- wrapped into a function of the `algorithms` table prepended to this body.
+--
+-- ARGV[1]             number of limits N
+-- ARGV[2 .. 3N+1]     per limit: algorithm name, key count, argument count
+-- ARGV[3N+2 ..]       per limit arguments, each followed by a dry-run flag,
+--                     '1' on any limit checking every limit without consuming
+-- KEYS                per limit keys, in the same order as the limits
+
+local count = tonumber(ARGV[1])
+local dry_run = false
+local calls = {}
+local key_index = 1
+local arg_index = 2 + count * 3
+
+for i = 1, count do
+    local base = 2 + (i - 1) * 3
+    local name = ARGV[base]
+    local key_count = tonumber(ARGV[base + 1])
+    local arg_count = tonumber(ARGV[base + 2])
+
+    local fn = algorithms[name]
+    if not fn then
+        return redis.error_reply('unknown algorithm ' .. tostring(name))
+    end
+
+    local keys = {}
+    for k = 1, key_count do
+        keys[k] = KEYS[key_index]
+        key_index = key_index + 1
+    end
+
+    local args = {}
+    for a = 1, arg_count do
+        args[a] = ARGV[arg_index]
+        arg_index = arg_index + 1
+    end
+
+    if args[arg_count] == '1' then
+        dry_run = true
+    end
+    calls[i] = {fn = fn, keys = keys, args = args}
+end
+
+-- First pass: check every limit without consuming anything
+local results = {}
+local allowed = true
+for i, call in ipairs(calls) do
+    call.args[#call.args] = '1'
+    results[i] = call.fn(call.keys, call.args)
+    if results[i][1] == 0 then
+        allowed = false
+    end
+end
+
+if not allowed or dry_run then
+    return results
+end
+
+-- Second pass: every limit passed, consume all of them
+for i, call in ipairs(calls) do
+    call.args[#call.args] = '0'
+    results[i] = call.fn(call.keys, call.args)
+end
+
+return results
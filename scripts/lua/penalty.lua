@@ -0,0 +1,47 @@
+-- Penalty box: bans clients whose requests are rejected too often.
+-- The ban key holds the strike number of the ban and expires when the ban ends.
+
+local ban_key = KEYS[1]
+local rejections_key = KEYS[2]
+local strikes_key = KEYS[3]
+local mode = ARGV[1]                        -- "check" reads the ban, "reject" counts a rejection
+
+-- Returns {banned, remaining ban in milliseconds, strike, newly banned}
+local remaining = redis.call('PTTL', ban_key)
+if remaining > 0 then
+    return {1, remaining, tonumber(redis.call('GET', ban_key)) or 0, 0}
+end
+
+if mode == 'check' then
+    return {0, 0, 0, 0}
+end
+
+local threshold = tonumber(ARGV[2])         -- Rejections within the window that ban the client
+local window = tonumber(ARGV[3])            -- Milliseconds rejections are counted over
+local duration = tonumber(ARGV[4])          -- Milliseconds of the first ban
+local multiplier = tonumber(ARGV[5])        -- Growth of every following ban
+local max_duration = tonumber(ARGV[6])      -- Milliseconds bans are capped at, 0 for no cap
+
+local rejections = redis.call('INCR', rejections_key)
+if rejections == 1 then
+    redis.call('PEXPIRE', rejections_key, window)
+end
+
+if rejections < threshold then
+    return {0, 0, 0, 0}
+end
+
+-- Ban the client, for longer at every strike
+redis.call('DEL', rejections_key)
+local strike = redis.call('INCR', strikes_key)
+local ban = duration * math.pow(multiplier, strike - 1)
+if max_duration > 0 and ban > max_duration then
+    ban = max_duration
+end
+ban = math.floor(ban)
+
+redis.call('SET', ban_key, strike, 'PX', ban)
+-- Strikes are forgotten once the client behaves for as long as the longest ban
+redis.call('PEXPIRE', strikes_key, ban + math.max(max_duration, duration))
+
+return {1, ban, strike, 1}
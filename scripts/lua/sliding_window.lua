@@ -4,6 +4,8 @@ local window = tonumber(ARGV[1])
 local limit = tonumber(ARGV[2])
 local now = tonumber(ARGV[3])
 local request_id = ARGV[4]
+local cost = tonumber(ARGV[5])
+local dry_run = ARGV[6] == '1'
 
 -- Remove old timestamps
 redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
@@ -19,14 +21,30 @@ if #oldest >= 2 then
     reset_time = math.ceil((tonumber(oldest[2]) + window) / 1000)
 end
 
-if count < limit then
-    redis.call('ZADD', key, now, request_id)
+if count + cost <= limit then
+    if dry_run then
+        if count == 0 then
+            reset_time = math.ceil((now + window) / 1000)
+        end
+        return {1, count + cost, limit - count - cost, reset_time}
+    end
+
+    -- One member per consumed unit, added in chunks to stay within unpack limits
+    local members = {}
+    for i = 1, cost do
+        table.insert(members, now)
+        table.insert(members, request_id .. ':' .. i)
+        if #members >= 1000 or i == cost then
+            redis.call('ZADD', key, unpack(members))
+            members = {}
+        end
+    end
     redis.call('EXPIRE', key, math.ceil(window / 1000))
     -- If this is first request, reset_time is now + window
     if count == 0 then
         reset_time = math.ceil((now + window) / 1000)
     end
-    return {1, count + 1, limit - count - 1, reset_time}
+    return {1, count + cost, limit - count - cost, reset_time}
 else
-    return {0, count, 0, reset_time}
-end
\ No newline at end of file
+    return {0, count, math.max(limit - count, 0), reset_time}
+end
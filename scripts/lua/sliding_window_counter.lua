@@ -0,0 +1,41 @@
+-- Sliding Window Counter Rate Limiter
+-- Approximates a sliding window with two fixed window counters: the current
+-- window count plus the previous window count weighted by its overlap.
+
+local current_key = KEYS[1]
+local previous_key = KEYS[2]
+local window = tonumber(ARGV[1]) -- Window size (in milliseconds)
+local limit = tonumber(ARGV[2])  -- Limit count
+local now = tonumber(ARGV[3])    -- Current timestamp (in milliseconds)
+local cost = tonumber(ARGV[4])   -- Units consumed by this request
+local dry_run = ARGV[5] == '1'   -- Check only, don't consume
+
+local window_start = now - (now % window)
+local elapsed = (now - window_start) / window
+
+local current = tonumber(redis.call('GET', current_key)) or 0
+local previous = tonumber(redis.call('GET', previous_key)) or 0
+
+-- Weight the previous window by the part of it still inside the sliding window
+local estimated = previous * (1 - elapsed) + current
+
+if estimated + cost > limit then
+    -- Find when the weighted previous count decays enough for this request
+    local reset_at = window_start + window
+    if previous > 0 and current + cost <= limit then
+        local overlap = (limit - cost - current) / previous
+        reset_at = window_start + math.ceil((1 - overlap) * window)
+    end
+    return {0, math.floor(estimated), math.max(math.floor(limit - estimated), 0), math.ceil(reset_at / 1000)}
+end
+
+if not dry_run then
+    current = redis.call('INCRBY', current_key, cost)
+    if current == cost then
+        -- The counter is still needed as the previous window of the next one
+        redis.call('PEXPIRE', current_key, window * 2)
+    end
+end
+
+estimated = estimated + cost
+return {1, math.ceil(estimated), math.floor(limit - estimated), math.ceil((window_start + window) / 1000)}
@@ -6,6 +6,7 @@ local refill_rate = tonumber(ARGV[2])       -- Tokens added per second
 local tokens_to_consume = tonumber(ARGV[3]) -- Tokens required for this request
 local now = tonumber(ARGV[4])               -- Current timestamp (in seconds)
 local bucket_ttl = tonumber(ARGV[5])        -- TTL for Redis key
+local dry_run = ARGV[6] == '1'              -- Check only, don't consume
 
 -- Get current bucket state
 local bucket = redis.call('HMGET', key, 'tokens', 'last_refill')
@@ -27,11 +28,18 @@ local reset_time = now + time_until_refill
 
 -- Check if enough tokens are available
 if tokens < tokens_to_consume then
-	redis.call('HSET', key, 'tokens', tokens, 'last_refill', last_refill)
-	redis.call('EXPIRE', key, bucket_ttl)
+	if not dry_run then
+		redis.call('HSET', key, 'tokens', tokens, 'last_refill', last_refill)
+		redis.call('EXPIRE', key, bucket_ttl)
+	end
 	return {0, math.floor(tokens), 0, reset_time}
 end
 
+if dry_run then
+	local left = math.floor(tokens - tokens_to_consume)
+	return {1, left, left, 0}
+end
+
 -- Consume tokens and save state
 tokens = tokens - tokens_to_consume
 redis.call('HSET', key, 'tokens', tokens, 'last_refill', last_refill)